@@ -0,0 +1,109 @@
+package kaniko
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// withBuildArgs returns the build with the build args of the args file and
+// the environment variables with the args prefix merged into the build args
+// of the flags, ArgsNew when IsMultipleBuildArgs is set and Args otherwise.
+// The args file comes first, sorted by name, followed by the environment
+// variables, sorted by name, and the build args of the flags. A name is only
+// passed once: the environment wins over the file and the flags win over both.
+func (b Build) withBuildArgs(environ []string) (Build, error) {
+	if b.ArgsFile == "" && b.ArgsFromEnv == "" {
+		return b, nil
+	}
+	values := make(map[string]string)
+	if b.ArgsFile != "" {
+		file, err := readBuildArgsFile(b.ArgsFile)
+		if err != nil {
+			return b, err
+		}
+		for key, value := range file {
+			values[key] = value
+		}
+	}
+	if b.ArgsFromEnv != "" {
+		for _, kv := range environ {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || !strings.HasPrefix(key, b.ArgsFromEnv) || key == b.ArgsFromEnv {
+				continue
+			}
+			values[strings.TrimPrefix(key, b.ArgsFromEnv)] = value
+		}
+	}
+
+	flags := b.Args
+	if b.IsMultipleBuildArgs {
+		flags = b.ArgsNew
+	}
+	for _, arg := range flags {
+		key, _, _ := strings.Cut(arg, "=")
+		delete(values, key)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, len(keys)+len(flags))
+	for _, key := range keys {
+		args = append(args, key+"="+values[key])
+	}
+	args = append(args, flags...)
+
+	if b.IsMultipleBuildArgs {
+		b.ArgsNew = args
+	} else {
+		b.Args = args
+	}
+	return b, nil
+}
+
+// readBuildArgsFile reads the build args of a JSON object when the file has
+// the .json extension, and of a dotenv file otherwise. JSON numbers and
+// booleans are passed as written.
+func readBuildArgsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build args file: %v", err)
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		values, err := godotenv.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid build args file %s: %v", path, err)
+		}
+		return values, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid build args file %s: %v", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var s string
+		switch value[0] {
+		case '"':
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, fmt.Errorf("invalid build arg %s in %s: %v", key, path, err)
+			}
+		case '{', '[', 'n':
+			return nil, fmt.Errorf("invalid build arg %s in %s: expected a string, number or boolean", key, path)
+		default:
+			s = string(value)
+		}
+		values[key] = s
+	}
+	return values, nil
+}
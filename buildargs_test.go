@@ -0,0 +1,107 @@
+package kaniko
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithBuildArgs(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "build.env")
+	if err := os.WriteFile(envFile, []byte("# build args\nVERSION=1.0\nCERT=\"-----BEGIN CERTIFICATE-----\\nMIIB\\n-----END CERTIFICATE-----\"\nLIST=a,b;c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonFile := filepath.Join(dir, "build.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"VERSION": "1.0", "RETRIES": 3, "DEBUG": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalidFile, []byte(`{"VERSION": ["1.0"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	environ := []string{"BUILD_VERSION=2.0", "BUILD_TOKEN=secret", "BUILD_=empty", "HOME=/root"}
+
+	tests := []struct {
+		name    string
+		build   Build
+		want    Build
+		wantErr bool
+	}{
+		{
+			name:  "none",
+			build: Build{Args: []string{"A=1"}},
+			want:  Build{Args: []string{"A=1"}},
+		},
+		{
+			name:  "dotenv file",
+			build: Build{ArgsFile: envFile, Args: []string{"A=1"}},
+			want: Build{ArgsFile: envFile, Args: []string{
+				"CERT=-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----",
+				"LIST=a,b;c",
+				"VERSION=1.0",
+				"A=1",
+			}},
+		},
+		{
+			name:  "json file",
+			build: Build{ArgsFile: jsonFile},
+			want:  Build{ArgsFile: jsonFile, Args: []string{"DEBUG=true", "RETRIES=3", "VERSION=1.0"}},
+		},
+		{
+			name:  "environment wins over the file",
+			build: Build{ArgsFile: jsonFile, ArgsFromEnv: "BUILD_"},
+			want:  Build{ArgsFile: jsonFile, ArgsFromEnv: "BUILD_", Args: []string{"DEBUG=true", "RETRIES=3", "TOKEN=secret", "VERSION=2.0"}},
+		},
+		{
+			name:  "flags win",
+			build: Build{ArgsFromEnv: "BUILD_", Args: []string{"VERSION=3.0"}},
+			want:  Build{ArgsFromEnv: "BUILD_", Args: []string{"TOKEN=secret", "VERSION=3.0"}},
+		},
+		{
+			name:  "multiple build args",
+			build: Build{ArgsFromEnv: "BUILD_", IsMultipleBuildArgs: true, Args: []string{"VERSION=3.0"}, ArgsNew: []string{"TOKEN=other"}},
+			want:  Build{ArgsFromEnv: "BUILD_", IsMultipleBuildArgs: true, Args: []string{"VERSION=3.0"}, ArgsNew: []string{"VERSION=2.0", "TOKEN=other"}},
+		},
+		{
+			name:    "missing file",
+			build:   Build{ArgsFile: filepath.Join(dir, "missing.env")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid json value",
+			build:   Build{ArgsFile: invalidFile},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build.withBuildArgs(environ)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expect error, got %v", got.Args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(Build{})); diff != "" {
+				t.Errorf("withBuildArgs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaskerArgsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "build.env")
+	if err := os.WriteFile(file, []byte("NPM_TOKEN=npm-secret-value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{Build: Build{ArgsFile: file}}
+	if got := p.masker().mask("npm error: npm-secret-value"); got != "npm error: "+redacted {
+		t.Errorf("mask() = %q", got)
+	}
+}
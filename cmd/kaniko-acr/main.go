@@ -11,10 +11,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -32,6 +31,7 @@ const (
 	dockerConfigPath   string = "/kaniko/.docker"
 	tenantKeyEnv       string = "AZURE_TENANT_ID"
 	certPathEnv        string = "AZURE_CLIENT_CERTIFICATE_PATH"
+	storageKeyEnv      string = "AZURE_STORAGE_ACCESS_KEY"
 	defaultDigestFile  string = "/kaniko/digest-file"
 	finalUrl           string = "https://portal.azure.com/#view/Microsoft_Azure_ContainerRegistries/TagMetadataBlade/registryId/"
 )
@@ -41,6 +41,8 @@ var (
 	pluginVersion = "unknown"
 	username      = "00000000-0000-0000-0000-000000000000"
 	maxPageCount  = 1000 // maximum count of pages to cycle through before we break out
+
+	managementURL = "https://management.azure.com"
 )
 
 func main() {
@@ -57,6 +59,36 @@ func main() {
 			Value:  "Dockerfile",
 			EnvVar: "PLUGIN_DOCKERFILE",
 		},
+		cli.StringFlag{
+			Name:   "dockerfile-content",
+			Usage:  "inline dockerfile content, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CONTENT",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url",
+			Usage:  "url of a remote dockerfile, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_URL",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-token",
+			Usage:  "bearer token of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-username",
+			Usage:  "basic auth username of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_USERNAME",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-password",
+			Usage:  "basic auth password of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-checksum",
+			Usage:  "expected sha256 checksum of the remote dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CHECKSUM",
+		},
 		cli.StringFlag{
 			Name:   "context",
 			Usage:  "build context",
@@ -85,6 +117,21 @@ func main() {
 			Usage:  "enable for semver tagging",
 			EnvVar: "PLUGIN_EXPAND_TAG",
 		},
+		cli.BoolFlag{
+			Name:   "floating-tags",
+			Usage:  "only move latest and the major and minor tags when the release is the highest of their series",
+			EnvVar: "PLUGIN_FLOATING_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "channel-tags",
+			Usage:  "tag pre-releases with the floating tags of their channel, e.g. 1.5-rc and rc",
+			EnvVar: "PLUGIN_CHANNEL_TAGS",
+		},
+		cli.StringSliceFlag{
+			Name:   "channels",
+			Usage:  "pre-release channels that get channel tags, defaults to rc, beta and alpha",
+			EnvVar: "PLUGIN_CHANNELS",
+		},
 		cli.BoolFlag{
 			Name:   "auto-tag",
 			Usage:  "enable auto generation of build tags",
@@ -95,6 +142,57 @@ func main() {
 			Usage:  "the suffix of auto build tags",
 			EnvVar: "PLUGIN_AUTO_TAG_SUFFIX",
 		},
+		cli.BoolFlag{
+			Name:   "auto-tag-branch",
+			Usage:  "auto tag builds of non-default branches with the sanitized branch name",
+			EnvVar: "PLUGIN_AUTO_TAG_BRANCH",
+		},
+		cli.BoolFlag{
+			Name:   "auto-tag-pull-request",
+			Usage:  "auto tag pull request builds with pr-<number>",
+			EnvVar: "PLUGIN_AUTO_TAG_PULL_REQUEST",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-component",
+			Usage:  "glob pattern of the component prefix of monorepo git tags",
+			EnvVar: "PLUGIN_AUTO_TAG_COMPONENT",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-scheme",
+			Usage:  "versioning scheme of auto tags and expanded tags, semver or calver",
+			Value:  "semver",
+			EnvVar: "PLUGIN_AUTO_TAG_SCHEME",
+		},
+		cli.BoolFlag{
+			Name:   "tag-remotely",
+			Usage:  "push the first tag only and create the other tags remotely with manifest-only PUTs",
+			EnvVar: "PLUGIN_TAG_REMOTELY",
+		},
+		cli.BoolFlag{
+			Name:   "verify-push",
+			Usage:  "verify every pushed tag resolves to the built digest and fail otherwise",
+			EnvVar: "PLUGIN_VERIFY_PUSH",
+		},
+		cli.BoolFlag{
+			Name:   "preflight",
+			Usage:  "check push access to the destination and cache repositories and pull access to the base images before the build",
+			EnvVar: "PLUGIN_PREFLIGHT",
+		},
+		cli.BoolFlag{
+			Name:   "preflight-warn-only",
+			Usage:  "report failed pre-flight checks without failing the build",
+			EnvVar: "PLUGIN_PREFLIGHT_WARN_ONLY",
+		},
+		cli.StringSliceFlag{
+			Name:   "immutable-tags",
+			Usage:  "tags that must not be overwritten, either all or glob patterns such as v*",
+			EnvVar: "PLUGIN_IMMUTABLE_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "dedup",
+			Usage:  "skip the build when an image with the same Dockerfile, build args, target, platform and context exists in the repository",
+			EnvVar: "PLUGIN_DEDUP",
+		},
 		cli.StringSliceFlag{
 			Name:   "args",
 			Usage:  "build args",
@@ -111,6 +209,16 @@ func main() {
 			Usage:  "plugin multiple build agrs",
 			EnvVar: "PLUGIN_MULTIPLE_BUILD_ARGS",
 		},
+		cli.StringFlag{
+			Name:   "args-file",
+			Usage:  "dotenv or json file of build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "args-from-env",
+			Usage:  "prefix of the environment variables passed as build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FROM_ENV",
+		},
 		cli.StringFlag{
 			Name:   "target",
 			Usage:  "build target",
@@ -131,6 +239,16 @@ func main() {
 			Usage:  "additional k=v labels",
 			EnvVar: "PLUGIN_CUSTOM_LABELS",
 		},
+		cli.BoolFlag{
+			Name:   "render-templates",
+			Usage:  "render label and build arg templates from the drone metadata",
+			EnvVar: "PLUGIN_RENDER_TEMPLATES",
+		},
+		cli.BoolFlag{
+			Name:   "oci-labels",
+			Usage:  "add the org.opencontainers.image labels populated from the drone metadata",
+			EnvVar: "PLUGIN_OCI_LABELS",
+		},
 		cli.StringFlag{
 			Name:   "registry",
 			Usage:  "ACR registry",
@@ -151,6 +269,11 @@ func main() {
 			Usage:  "Docker password for base image registry",
 			EnvVar: "PLUGIN_DOCKER_PASSWORD,PLUGIN_BASE_IMAGE_PASSWORD,DOCKER_PASSWORD",
 		},
+		cli.StringFlag{
+			Name:   "registry-credentials",
+			Usage:  "json array of additional registry credentials",
+			EnvVar: "PLUGIN_REGISTRY_CREDENTIALS",
+		},
 		cli.StringSliceFlag{
 			Name:   "registry-mirrors",
 			Usage:  "docker registry mirrors",
@@ -231,16 +354,36 @@ func main() {
 			Usage:  "Set this flag if you only want to build the image, without pushing to a registry",
 			EnvVar: "PLUGIN_NO_PUSH",
 		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "Print the resolved build plan as JSON without building, pushing or calling any cloud API",
+			EnvVar: "PLUGIN_DRY_RUN",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-patterns",
+			Usage:  "Name patterns of build args and environment variables whose values are masked in logs",
+			EnvVar: "PLUGIN_REDACT_PATTERNS",
+		},
 		cli.BoolFlag{
 			Name:   "push-only",
 			Usage:  "Set this flag if you only want to push a pre-built image from a tarball",
 			EnvVar: "PLUGIN_PUSH_ONLY",
 		},
+		cli.StringFlag{
+			Name:   "promote-from",
+			Usage:  "Image reference (tag or digest) to copy to the destination tags instead of building",
+			EnvVar: "PLUGIN_PROMOTE_FROM",
+		},
 		cli.StringFlag{
 			Name:   "source-tar-path",
 			Usage:  "Path to the local tarball to be pushed when push-only is set",
 			EnvVar: "PLUGIN_SOURCE_TAR_PATH",
 		},
+		cli.StringFlag{
+			Name:   "source-tag",
+			Usage:  "Tag of the image to push when the source tarball or OCI layout holds several images",
+			EnvVar: "PLUGIN_SOURCE_TAG",
+		},
 		cli.StringFlag{
 			Name:   "tar-path",
 			Usage:  "Set this flag to save the image as a tarball at path",
@@ -256,6 +399,11 @@ func main() {
 			Usage:  "Allows to build with another default platform than the host, similarly to docker build --platform",
 			EnvVar: "PLUGIN_PLATFORM,PLUGIN_CUSTOM_PLATFORM",
 		},
+		cli.StringSliceFlag{
+			Name:   "platforms",
+			Usage:  "Build one image per platform and publish them as a multi-platform image index, e.g. linux/amd64,linux/arm64",
+			EnvVar: "PLUGIN_PLATFORMS",
+		},
 		cli.BoolFlag{
 			Name:   "skip-unused-stages",
 			Usage:  "build only used stages",
@@ -415,8 +563,10 @@ func main() {
 }
 
 func run(c *cli.Context) error {
+	dryRun := c.Bool("dry-run")
+
 	// Check if push-only flag is set
-	if c.Bool("push-only") {
+	if c.Bool("push-only") && !dryRun {
 		return handlePushOnly(c)
 	}
 
@@ -428,51 +578,137 @@ func run(c *cli.Context) error {
 	oidcIdToken := c.String("oidc-token-id")
 	authorityHost := c.String("azure-authority-host")
 
-	var publicUrl string
-	var err error
-	publicUrl, err = setupAuth(
-		tenantID,
-		clientID,
-		oidcIdToken,
-		c.String("client-cert"),
-		c.String("client-secret"),
-		c.String("subscription-id"),
-		registry,
-		c.String("base-image-username"),
-		c.String("base-image-password"),
-		c.String("base-image-registry"),
-		authorityHost,
-		noPush,
-	)
+	// the additional registry credentials are written first, so that the
+	// credentials of the plugin settings take precedence over them
+	extraCredentials, err := docker.ParseRegistryCredentials(c.String("registry-credentials"))
 	if err != nil {
-		return err
+		return errors.Wrap(err, "invalid registry credentials")
+	}
+	if len(extraCredentials) > 0 && !dryRun {
+		if err := docker.NewConfig().CreateDockerConfig(extraCredentials, dockerConfigPath); err != nil {
+			return errors.Wrap(err, "failed to create docker config")
+		}
+	}
+
+	var publicUrl string
+	var registries []string
+	if dryRun {
+		registries = dryRunRegistries(
+			registry,
+			c.String("base-image-username"),
+			c.String("base-image-password"),
+			c.String("base-image-registry"),
+		)
+		registries = docker.AppendRegistries(registries, extraCredentials)
+	} else {
+		var err error
+		publicUrl, err = setupAuth(
+			tenantID,
+			clientID,
+			oidcIdToken,
+			c.String("client-cert"),
+			c.String("client-secret"),
+			c.String("subscription-id"),
+			registry,
+			c.String("base-image-username"),
+			c.String("base-image-password"),
+			c.String("base-image-registry"),
+			authorityHost,
+			noPush,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the executor downloads an Azure Blob Storage build context with the
+	// access key of the storage account, listed with the registry credentials
+	if !dryRun && kaniko.ContextType(c.String("context")) == kaniko.ContextAzureBlob && os.Getenv(storageKeyEnv) == "" {
+		if err := setStorageAuth(
+			c.String("context"),
+			tenantID,
+			clientID,
+			oidcIdToken,
+			c.String("client-cert"),
+			c.String("client-secret"),
+			c.String("subscription-id"),
+			authorityHost,
+		); err != nil {
+			return errors.Wrap(err, "failed to set up Azure Blob Storage build context credentials")
+		}
+	}
+
+	plugin := newPlugin(c, publicUrl)
+	plugin.Registries = registries
+	// the registry calls the plugin makes itself, e.g. to verify the push or
+	// to tag remotely, resolve the credentials from the docker config written by setupAuth
+	if plugin.Build.CallsRegistry() && !dryRun {
+		if err := os.Setenv("DOCKER_CONFIG", dockerConfigPath); err != nil {
+			return fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
+		}
 	}
 
+	return plugin.Exec()
+}
+
+// newPlugin returns the plugin of the settings. The build and the push-only
+// operation both use it, so that they honour the same settings.
+func newPlugin(c *cli.Context, publicUrl string) kaniko.Plugin {
 	plugin := kaniko.Plugin{
 		Build: kaniko.Build{
 			DroneCommitRef:              c.String("drone-commit-ref"),
 			DroneRepoBranch:             c.String("drone-repo-branch"),
 			Dockerfile:                  c.String("dockerfile"),
+			DockerfileContent:           c.String("dockerfile-content"),
+			DockerfileURL:               c.String("dockerfile-url"),
+			DockerfileToken:             c.String("dockerfile-url-token"),
+			DockerfileUsername:          c.String("dockerfile-url-username"),
+			DockerfilePassword:          c.String("dockerfile-url-password"),
+			DockerfileChecksum:          c.String("dockerfile-checksum"),
 			Context:                     c.String("context"),
 			Tags:                        c.StringSlice("tags"),
 			AutoTag:                     c.Bool("auto-tag"),
 			AutoTagSuffix:               c.String("auto-tag-suffix"),
+			AutoTagBranch:               c.Bool("auto-tag-branch"),
+			AutoTagPullRequest:          c.Bool("auto-tag-pull-request"),
+			AutoTagComponent:            c.String("auto-tag-component"),
+			AutoTagScheme:               c.String("auto-tag-scheme"),
 			ExpandTag:                   c.Bool("expand-tag"),
+			FloatingTags:                c.Bool("floating-tags"),
+			ChannelTags:                 c.Bool("channel-tags"),
+			Channels:                    c.StringSlice("channels"),
+			TagRemotely:                 c.Bool("tag-remotely"),
+			VerifyPush:                  c.Bool("verify-push"),
+			Preflight:                   c.Bool("preflight"),
+			PreflightWarnOnly:           c.Bool("preflight-warn-only"),
+			ImmutableTags:               c.StringSlice("immutable-tags"),
+			Dedup:                       c.Bool("dedup"),
 			Args:                        c.StringSlice("args"),
 			ArgsNew:                     c.Generic("args-new").(*utils.CustomStringSliceFlag).GetValue(),
 			IsMultipleBuildArgs:         c.Bool("plugin-multiple-build-agrs"),
+			ArgsFile:                    c.String("args-file"),
+			ArgsFromEnv:                 c.String("args-from-env"),
 			Target:                      c.String("target"),
 			Repo:                        c.String("repo"),
 			Mirrors:                     c.StringSlice("registry-mirrors"),
 			Labels:                      c.StringSlice("custom-labels"),
+			RenderTemplates:             c.Bool("render-templates"),
+			OCILabels:                   c.Bool("oci-labels"),
 			SnapshotMode:                c.String("snapshot-mode"),
 			EnableCache:                 c.Bool("enable-cache"),
 			CacheRepo:                   fmt.Sprintf("%s/%s", c.String("registry"), c.String("cache-repo")),
 			CacheTTL:                    c.Int("cache-ttl"),
 			DigestFile:                  defaultDigestFile,
-			NoPush:                      noPush,
+			NoPush:                      c.Bool("no-push"),
+			DryRun:                      c.Bool("dry-run"),
+			RedactPatterns:              c.StringSlice("redact-patterns"),
+			PushOnly:                    c.Bool("push-only"),
+			PromoteFrom:                 c.String("promote-from"),
+			SourceTarPath:               c.String("source-tar-path"),
+			SourceTag:                   c.String("source-tag"),
 			Verbosity:                   c.String("verbosity"),
 			CustomPlatform:              c.String("platform"),
+			Platforms:                   c.StringSlice("platforms"),
 			SkipUnusedStages:            c.Bool("skip-unused-stages"),
 			CacheDir:                    c.String("cache-dir"),
 			CacheCopyLayers:             c.Bool("cache-copy-layers"),
@@ -526,8 +762,7 @@ func run(c *cli.Context) error {
 	if c.IsSet("tar-path") {
 		plugin.Build.TarPath = c.String("tar-path")
 	}
-
-	return plugin.Exec()
+	return plugin
 }
 
 func setupAuth(tenantId, clientId, oidcIdToken, cert,
@@ -798,8 +1033,140 @@ func getPublicUrl(token, registryUrl, subscriptionId string) (string, error) {
 	return "", errors.New("did not receive any registry information from /subscriptions API")
 }
 
+// setStorageAuth exports the access key of the storage account of the build
+// context, as the executor only supports shared key authentication.
+func setStorageAuth(buildContext, tenantId, clientId, oidcIdToken, cert, clientSecret, subscriptionId, authorityHost string) error {
+	if subscriptionId == "" {
+		return fmt.Errorf("subscription id must be specified to list the keys of the storage account")
+	}
+	u, err := url.Parse(buildContext)
+	if err != nil {
+		return errors.Wrap(err, "invalid build context")
+	}
+	account := strings.Split(u.Host, ".")[0]
+
+	token, err := getManagementToken(tenantId, clientId, oidcIdToken, cert, clientSecret, authorityHost)
+	if err != nil {
+		return err
+	}
+	key, err := getStorageAccountKey(token, subscriptionId, account)
+	if err != nil {
+		return err
+	}
+	if err := os.Setenv(storageKeyEnv, key); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", storageKeyEnv))
+	}
+	return nil
+}
+
+// getManagementToken returns an Azure Resource Manager access token of the
+// OIDC token, the client secret, the client certificate or the managed
+// identity, in that order.
+func getManagementToken(tenantId, clientId, oidcIdToken, cert, clientSecret, authorityHost string) (string, error) {
+	if oidcIdToken != "" {
+		token, err := azureutil.GetAADAccessTokenViaClientAssertion(context.Background(), tenantId, clientId, oidcIdToken, authorityHost)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get AAD token via OIDC")
+		}
+		return token, nil
+	}
+
+	var cred azcore.TokenCredential
+	var err error
+	switch {
+	case clientSecret != "":
+		cred, err = azidentity.NewClientSecretCredential(tenantId, clientId, clientSecret, nil)
+	case cert != "":
+		decoded, decodeErr := base64.StdEncoding.DecodeString(cert)
+		if decodeErr != nil {
+			return "", errors.Wrap(decodeErr, "failed to base64 decode ACR certificate")
+		}
+		certs, key, parseErr := azidentity.ParseCertificates(decoded, nil)
+		if parseErr != nil {
+			return "", errors.Wrap(parseErr, "failed to parse ACR certificate")
+		}
+		cred, err = azidentity.NewClientCertificateCredential(tenantId, clientId, certs, key, nil)
+	default:
+		cred, err = azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{TenantID: tenantId})
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get credentials")
+	}
+	azToken, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{
+		Scopes: []string{"https://management.azure.com/.default"},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to fetch access token")
+	}
+	return azToken.Token, nil
+}
+
+// getStorageAccountKey looks up the storage account in the subscription and
+// returns its first access key.
+func getStorageAccountKey(token, subscriptionId, account string) (string, error) {
+	client := &http.Client{}
+	lookupURL := managementURL + "/subscriptions/" + subscriptionId +
+		"/resources?$filter=resourceType%20eq%20'Microsoft.Storage/storageAccounts'%20and%20name%20eq%20'" +
+		account + "'&api-version=2021-04-01&$select=id"
+	req, err := http.NewRequest(http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create request for getting storage account")
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	res, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send request for getting storage account")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to look up storage account %s: %s", account, res.Status)
+	}
+	var resources strct
+	if err := json.NewDecoder(res.Body).Decode(&resources); err != nil {
+		return "", errors.Wrap(err, "failed to decode storage account")
+	}
+	if len(resources.Value) == 0 || resources.Value[0].ID == "" {
+		return "", fmt.Errorf("storage account %s not found in subscription %s", account, subscriptionId)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, managementURL+resources.Value[0].ID+"/listKeys?api-version=2023-01-01", nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create request for listing storage account keys")
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	res, err = client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send request for listing storage account keys")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to list the keys of storage account %s: %s", account, res.Status)
+	}
+	var keys struct {
+		Keys []struct {
+			Value string `json:"value"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&keys); err != nil {
+		return "", errors.Wrap(err, "failed to decode storage account keys")
+	}
+	if len(keys.Keys) == 0 || keys.Keys[0].Value == "" {
+		return "", fmt.Errorf("storage account %s has no access key", account)
+	}
+	return keys.Keys[0].Value, nil
+}
+
 func setDockerAuth(username, password, registry, dockerUsername, dockerPassword, dockerRegistry string) error {
 	dockerConfig := docker.NewConfig()
+	credentials := registryCredentials(username, password, registry, dockerUsername, dockerPassword, dockerRegistry)
+	if dockerRegistry == "" {
+		fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
+			"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
+	}
+	return dockerConfig.CreateDockerConfig(credentials, dockerConfigPath)
+}
+
+func registryCredentials(username, password, registry, dockerUsername, dockerPassword, dockerRegistry string) []docker.RegistryCredentials {
 	pushToRegistryCreds := docker.RegistryCredentials{
 		Registry: registry,
 		Username: username,
@@ -815,11 +1182,14 @@ func setDockerAuth(username, password, registry, dockerUsername, dockerPassword,
 			Password: dockerPassword,
 		}
 		credentials = append(credentials, pullFromRegistryCreds)
-	} else {
-		fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
-			"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
 	}
-	return dockerConfig.CreateDockerConfig(credentials, dockerConfigPath)
+	return credentials
+}
+
+// dryRunRegistries returns the registries setupAuth would configure
+// credentials for, without calling any Azure API for the ACR token.
+func dryRunRegistries(registry, dockerUsername, dockerPassword, dockerRegistry string) []string {
+	return docker.AppendRegistries(nil, registryCredentials(username, "", registry, dockerUsername, dockerPassword, dockerRegistry))
 }
 
 func encodeParam(s string) string {
@@ -869,14 +1239,6 @@ func handlePushOnly(c *cli.Context) error {
 		return err
 	}
 
-	// Load the image from the tarball
-	logrus.Infof("Loading image from tarball: %s", sourceTarPath)
-
-	img, err := crane.Load(sourceTarPath)
-	if err != nil {
-		return fmt.Errorf("failed to load image from tarball: %v", err)
-	}
-
 	// Check if the Docker config directory exists (should have been created by setupAuth)
 	if _, err := os.Stat(dockerConfigPath); os.IsNotExist(err) {
 		return fmt.Errorf("Docker config directory does not exist: %v", err)
@@ -889,38 +1251,9 @@ func handlePushOnly(c *cli.Context) error {
 		return fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
 	}
 
-	// Setup crane options
-	opts := []crane.Option{
-		crane.WithAuthFromKeychain(authn.DefaultKeychain),
-	}
-
-	// Push for each tag
-	tags := c.StringSlice("tags")
-	if len(tags) == 0 {
-		tags = []string{"latest"}
-	}
-
-	// Use the registry from setupAuth if publicUrl is available, otherwise use the provided registry
-	pushRegistry := registry
-	if publicUrl != "" {
-		logrus.Infof("Using public URL for pushing: %s", publicUrl)
-		// Extract just the registry part from the full URL if needed
-		// This depends on the format of publicUrl, adjust parsing as needed
-		pushRegistry = publicUrl
-	}
-
-	for _, tag := range tags {
-		dest := fmt.Sprintf("%s/%s:%s", pushRegistry, repo, tag)
-		logrus.Infof("Pushing image to: %s", dest)
-
-		if err := crane.Push(img, dest, opts...); err != nil {
-			return fmt.Errorf("failed to push image to %s: %v", dest, err)
-		}
-
-		logrus.Infof("Successfully pushed image to %s", dest)
-	}
-
-	return nil
+	plugin := newPlugin(c, publicUrl)
+	plugin.Build.PushOnly = true
+	return plugin.Push()
 }
 
 type strct struct {
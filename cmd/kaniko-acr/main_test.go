@@ -4,8 +4,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/drone/drone-kaniko/pkg/docker"
@@ -436,3 +439,54 @@ func TestGetACRToken_ManagedIdentity_NoTenantId(t *testing.T) {
 	// because tenantId is missing and no credentials are available
 	assert.Contains(t, err.Error(), "failed to fetch access token")
 }
+
+func TestGetStorageAccountKey(t *testing.T) {
+	const id = "/subscriptions/sub/resourceGroups/builds/providers/Microsoft.Storage/storageAccounts/contexts"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/sub/resources":
+			if strings.Contains(r.URL.Query().Get("$filter"), "'contexts'") {
+				w.Write([]byte(`{"value": [{"id": "` + id + `"}]}`))
+			} else {
+				w.Write([]byte(`{"value": []}`))
+			}
+		case r.Method == http.MethodPost && r.URL.Path == id+"/listKeys":
+			w.Write([]byte(`{"keys": [{"keyName": "key1", "value": "storage-key"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { managementURL = url }(managementURL)
+	managementURL = server.URL
+
+	key, err := getStorageAccountKey("token", "sub", "contexts")
+	assert.NoError(t, err)
+	assert.Equal(t, "storage-key", key)
+
+	_, err = getStorageAccountKey("token", "sub", "missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "storage account missing not found")
+
+	_, err = getStorageAccountKey("expired", "sub", "contexts")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to look up storage account contexts: 401 Unauthorized")
+}
+
+func TestSetStorageAuth_SubscriptionMustBeSpecified(t *testing.T) {
+	err := setStorageAuth("https://contexts.blob.core.windows.net/builds/context.tar.gz", "tenant", "client", "", "", "secret", "", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "subscription id must be specified")
+}
+
+func TestDryRunRegistries(t *testing.T) {
+	assert.Equal(t, []string{"myregistry.azurecr.io"}, dryRunRegistries("myregistry.azurecr.io", "", "", ""))
+	assert.Equal(t,
+		[]string{"myregistry.azurecr.io", "https://index.docker.io/v1/"},
+		dryRunRegistries("myregistry.azurecr.io", "user", "pass", "https://index.docker.io/v2/"),
+	)
+}
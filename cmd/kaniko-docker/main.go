@@ -50,6 +50,36 @@ func main() {
 			Value:  "Dockerfile",
 			EnvVar: "PLUGIN_DOCKERFILE",
 		},
+		cli.StringFlag{
+			Name:   "dockerfile-content",
+			Usage:  "inline dockerfile content, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CONTENT",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url",
+			Usage:  "url of a remote dockerfile, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_URL",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-token",
+			Usage:  "bearer token of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-username",
+			Usage:  "basic auth username of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_USERNAME",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-password",
+			Usage:  "basic auth password of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-checksum",
+			Usage:  "expected sha256 checksum of the remote dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CHECKSUM",
+		},
 		cli.StringFlag{
 			Name:   "context",
 			Usage:  "build context",
@@ -83,6 +113,21 @@ func main() {
 			Usage:  "enable for semver tagging",
 			EnvVar: "PLUGIN_EXPAND_TAG",
 		},
+		cli.BoolFlag{
+			Name:   "floating-tags",
+			Usage:  "only move latest and the major and minor tags when the release is the highest of their series",
+			EnvVar: "PLUGIN_FLOATING_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "channel-tags",
+			Usage:  "tag pre-releases with the floating tags of their channel, e.g. 1.5-rc and rc",
+			EnvVar: "PLUGIN_CHANNEL_TAGS",
+		},
+		cli.StringSliceFlag{
+			Name:   "channels",
+			Usage:  "pre-release channels that get channel tags, defaults to rc, beta and alpha",
+			EnvVar: "PLUGIN_CHANNELS",
+		},
 		cli.BoolFlag{
 			Name:   "auto-tag",
 			Usage:  "enable auto generation of build tags",
@@ -90,14 +135,65 @@ func main() {
 		},
 		cli.StringFlag{
 			Name:   "dockerconfig",
-			Usage:  "docker json dockerconfig",
-			EnvVar: "PLUGIN_CONFIG",
+			Usage:  "docker json dockerconfig, merged on top of the existing docker config",
+			EnvVar: "PLUGIN_CONFIG,PLUGIN_DOCKERCONFIG",
 		},
 		cli.StringFlag{
 			Name:   "auto-tag-suffix",
 			Usage:  "the suffix of auto build tags",
 			EnvVar: "PLUGIN_AUTO_TAG_SUFFIX",
 		},
+		cli.BoolFlag{
+			Name:   "auto-tag-branch",
+			Usage:  "auto tag builds of non-default branches with the sanitized branch name",
+			EnvVar: "PLUGIN_AUTO_TAG_BRANCH",
+		},
+		cli.BoolFlag{
+			Name:   "auto-tag-pull-request",
+			Usage:  "auto tag pull request builds with pr-<number>",
+			EnvVar: "PLUGIN_AUTO_TAG_PULL_REQUEST",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-component",
+			Usage:  "glob pattern of the component prefix of monorepo git tags",
+			EnvVar: "PLUGIN_AUTO_TAG_COMPONENT",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-scheme",
+			Usage:  "versioning scheme of auto tags and expanded tags, semver or calver",
+			Value:  "semver",
+			EnvVar: "PLUGIN_AUTO_TAG_SCHEME",
+		},
+		cli.BoolFlag{
+			Name:   "tag-remotely",
+			Usage:  "push the first tag only and create the other tags remotely with manifest-only PUTs",
+			EnvVar: "PLUGIN_TAG_REMOTELY",
+		},
+		cli.BoolFlag{
+			Name:   "verify-push",
+			Usage:  "verify every pushed tag resolves to the built digest and fail otherwise",
+			EnvVar: "PLUGIN_VERIFY_PUSH",
+		},
+		cli.BoolFlag{
+			Name:   "preflight",
+			Usage:  "check push access to the destination and cache repositories and pull access to the base images before the build",
+			EnvVar: "PLUGIN_PREFLIGHT",
+		},
+		cli.BoolFlag{
+			Name:   "preflight-warn-only",
+			Usage:  "report failed pre-flight checks without failing the build",
+			EnvVar: "PLUGIN_PREFLIGHT_WARN_ONLY",
+		},
+		cli.StringSliceFlag{
+			Name:   "immutable-tags",
+			Usage:  "tags that must not be overwritten, either all or glob patterns such as v*",
+			EnvVar: "PLUGIN_IMMUTABLE_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "dedup",
+			Usage:  "skip the build when an image with the same Dockerfile, build args, target, platform and context exists in the repository",
+			EnvVar: "PLUGIN_DEDUP",
+		},
 		cli.StringSliceFlag{
 			Name:   "args",
 			Usage:  "build args",
@@ -114,6 +210,16 @@ func main() {
 			Usage:  "plugin multiple build agrs",
 			EnvVar: "PLUGIN_MULTIPLE_BUILD_ARGS",
 		},
+		cli.StringFlag{
+			Name:   "args-file",
+			Usage:  "dotenv or json file of build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "args-from-env",
+			Usage:  "prefix of the environment variables passed as build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FROM_ENV",
+		},
 		cli.StringFlag{
 			Name:   "target",
 			Usage:  "build target",
@@ -129,6 +235,16 @@ func main() {
 			Usage:  "additional k=v labels",
 			EnvVar: "PLUGIN_CUSTOM_LABELS",
 		},
+		cli.BoolFlag{
+			Name:   "render-templates",
+			Usage:  "render label and build arg templates from the drone metadata",
+			EnvVar: "PLUGIN_RENDER_TEMPLATES",
+		},
+		cli.BoolFlag{
+			Name:   "oci-labels",
+			Usage:  "add the org.opencontainers.image labels populated from the drone metadata",
+			EnvVar: "PLUGIN_OCI_LABELS",
+		},
 		cli.StringFlag{
 			Name:   "registry",
 			Usage:  "docker registry of registry to push image to",
@@ -140,6 +256,11 @@ func main() {
 			Usage:  "Docker registry for base image",
 			EnvVar: "PLUGIN_DOCKER_REGISTRY,PLUGIN_BASE_IMAGE_REGISTRY,DOCKER_REGISTRY",
 		},
+		cli.StringFlag{
+			Name:   "registry-credentials",
+			Usage:  "json array of additional registry credentials",
+			EnvVar: "PLUGIN_REGISTRY_CREDENTIALS",
+		},
 		cli.StringSliceFlag{
 			Name:   "registry-mirrors",
 			Usage:  "docker registry mirrors",
@@ -200,6 +321,16 @@ func main() {
 			Usage:  "Set this flag if you only want to build the image, without pushing to a registry",
 			EnvVar: "PLUGIN_NO_PUSH",
 		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "Print the resolved build plan as JSON without building, pushing or calling any cloud API",
+			EnvVar: "PLUGIN_DRY_RUN",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-patterns",
+			Usage:  "Name patterns of build args and environment variables whose values are masked in logs",
+			EnvVar: "PLUGIN_REDACT_PATTERNS",
+		},
 		cli.StringFlag{
 			Name:   "tar-path",
 			Usage:  "Set this flag to save the image as a tarball at path",
@@ -215,6 +346,11 @@ func main() {
 			Usage:  "Allows to build with another default platform than the host, similarly to docker build --platform",
 			EnvVar: "PLUGIN_PLATFORM,PLUGIN_CUSTOM_PLATFORM",
 		},
+		cli.StringSliceFlag{
+			Name:   "platforms",
+			Usage:  "Build one image per platform and publish them as a multi-platform image index, e.g. linux/amd64,linux/arm64",
+			EnvVar: "PLUGIN_PLATFORMS",
+		},
 		cli.BoolFlag{
 			Name:   "skip-unused-stages",
 			Usage:  "build only used stages",
@@ -382,11 +518,21 @@ func main() {
 			Usage:  "Set this flag for the source tarball during push operations.",
 			EnvVar: "PLUGIN_SOURCE_TAR_PATH",
 		},
+		cli.StringFlag{
+			Name:   "source-tag",
+			Usage:  "Tag of the image to push when the source tarball or OCI layout holds several images",
+			EnvVar: "PLUGIN_SOURCE_TAG",
+		},
 		cli.BoolFlag{
 			Name:   "push-only",
 			Usage:  "Specify if the operation is push-only",
 			EnvVar: "PLUGIN_PUSH_ONLY",
 		},
+		cli.StringFlag{
+			Name:   "promote-from",
+			Usage:  "Image reference (tag or digest) to copy to the destination tags instead of building",
+			EnvVar: "PLUGIN_PROMOTE_FROM",
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -397,25 +543,55 @@ func main() {
 func run(c *cli.Context) error {
 	username := c.String("username")
 	noPush := c.Bool("no-push")
+	dryRun := c.Bool("dry-run")
 	configOverride := c.String("dockerconfig")
-	// if configOverride is provided, use this directly to write to docker config file
-	if len(configOverride) > 0 {
-		if err := docker.WriteDockerConfig([]byte(configOverride), dockerPath); err != nil {
-			return err
-		}
-	} else if !noPush || username != "" {
-		// setup auth when pushing/pulling or credentials are defined and docker config override is false
-		err := setDockerAuth(
+	extraCredentials, err := docker.ParseRegistryCredentials(c.String("registry-credentials"))
+	if err != nil {
+		return errors.Wrap(err, "invalid registry credentials")
+	}
+	var registries []string
+	if dryRun {
+		registries, err = dryRunRegistries(
+			configOverride,
 			c.String("username"),
 			c.String("password"),
 			c.String("registry"),
 			c.String("base-image-username"),
 			c.String("base-image-password"),
 			c.String("base-image-registry"),
+			noPush,
 		)
 		if err != nil {
 			return errors.Wrap(err, "failed to create docker config")
 		}
+		registries = docker.AppendRegistries(registries, extraCredentials)
+	} else {
+		// the docker config override is merged on top of the existing docker
+		// config, then the additional registry credentials and finally the
+		// credentials generated from the username and password
+		if len(configOverride) > 0 {
+			if err := mergeDockerConfig(configOverride); err != nil {
+				return errors.Wrap(err, "failed to create docker config")
+			}
+		}
+		if len(extraCredentials) > 0 {
+			if err := docker.NewConfig().CreateDockerConfig(extraCredentials, dockerPath); err != nil {
+				return errors.Wrap(err, "failed to create docker config")
+			}
+		}
+		if generateCredentials(configOverride, username, noPush) {
+			err := setDockerAuth(
+				c.String("username"),
+				c.String("password"),
+				c.String("registry"),
+				c.String("base-image-username"),
+				c.String("base-image-password"),
+				c.String("base-image-registry"),
+			)
+			if err != nil {
+				return errors.Wrap(err, "failed to create docker config")
+			}
+		}
 	}
 
 	plugin := kaniko.Plugin{
@@ -423,18 +599,41 @@ func run(c *cli.Context) error {
 			DroneCommitRef:              c.String("drone-commit-ref"),
 			DroneRepoBranch:             c.String("drone-repo-branch"),
 			Dockerfile:                  c.String("dockerfile"),
+			DockerfileContent:           c.String("dockerfile-content"),
+			DockerfileURL:               c.String("dockerfile-url"),
+			DockerfileToken:             c.String("dockerfile-url-token"),
+			DockerfileUsername:          c.String("dockerfile-url-username"),
+			DockerfilePassword:          c.String("dockerfile-url-password"),
+			DockerfileChecksum:          c.String("dockerfile-checksum"),
 			Context:                     c.String("context"),
 			Tags:                        c.StringSlice("tags"),
 			AutoTag:                     c.Bool("auto-tag"),
 			AutoTagSuffix:               c.String("auto-tag-suffix"),
+			AutoTagBranch:               c.Bool("auto-tag-branch"),
+			AutoTagPullRequest:          c.Bool("auto-tag-pull-request"),
+			AutoTagComponent:            c.String("auto-tag-component"),
+			AutoTagScheme:               c.String("auto-tag-scheme"),
 			ExpandTag:                   c.Bool("expand-tag"),
+			FloatingTags:                c.Bool("floating-tags"),
+			ChannelTags:                 c.Bool("channel-tags"),
+			Channels:                    c.StringSlice("channels"),
+			TagRemotely:                 c.Bool("tag-remotely"),
+			VerifyPush:                  c.Bool("verify-push"),
+			Preflight:                   c.Bool("preflight"),
+			PreflightWarnOnly:           c.Bool("preflight-warn-only"),
+			ImmutableTags:               c.StringSlice("immutable-tags"),
+			Dedup:                       c.Bool("dedup"),
 			Args:                        c.StringSlice("args"),
 			ArgsNew:                     c.Generic("args-new").(*utils.CustomStringSliceFlag).GetValue(),
 			IsMultipleBuildArgs:         c.Bool("plugin-multiple-build-agrs"),
+			ArgsFile:                    c.String("args-file"),
+			ArgsFromEnv:                 c.String("args-from-env"),
 			Target:                      c.String("target"),
 			Repo:                        buildRepo(c.String("registry"), c.String("repo"), c.Bool("expand-repo")),
 			Mirrors:                     c.StringSlice("registry-mirrors"),
 			Labels:                      c.StringSlice("custom-labels"),
+			RenderTemplates:             c.Bool("render-templates"),
+			OCILabels:                   c.Bool("oci-labels"),
 			SkipTlsVerify:               c.Bool("skip-tls-verify"),
 			SnapshotMode:                c.String("snapshot-mode"),
 			EnableCache:                 c.Bool("enable-cache"),
@@ -442,10 +641,14 @@ func run(c *cli.Context) error {
 			CacheTTL:                    c.Int("cache-ttl"),
 			DigestFile:                  defaultDigestFile,
 			NoPush:                      noPush,
+			DryRun:                      dryRun,
+			RedactPatterns:              c.StringSlice("redact-patterns"),
 			TarPath:                     c.String("tar-path"),
 			Verbosity:                   c.String("verbosity"),
 			CustomPlatform:              c.String("platform"),
+			Platforms:                   c.StringSlice("platforms"),
 			PushOnly:                    c.Bool("push-only"),
+			PromoteFrom:                 c.String("promote-from"),
 			SkipUnusedStages:            c.Bool("skip-unused-stages"),
 			CacheDir:                    c.String("cache-dir"),
 			CacheCopyLayers:             c.Bool("cache-copy-layers"),
@@ -473,6 +676,7 @@ func run(c *cli.Context) error {
 			SkipTLSVerifyPull:           c.Bool("skip-tls-verify-pull"),
 			SkipTLSVerifyRegistry:       c.Bool("skip-tls-verify-registry"),
 			SourceTarPath:               c.String("source-tar-path"),
+			SourceTag:                   c.String("source-tag"),
 			UseNewRun:                   c.Bool("use-new-run"),
 			IgnorePath:                  c.String("ignore-path"),
 			IgnorePaths:                 c.StringSlice("ignore-paths"),
@@ -490,6 +694,14 @@ func run(c *cli.Context) error {
 		Output: kaniko.Output{
 			OutputFile: c.String("output-file"),
 		},
+		Registries: registries,
+	}
+	// the registry calls the plugin makes itself, e.g. to verify the push or
+	// to tag remotely, resolve the credentials from the docker config
+	if plugin.Build.CallsRegistry() && !dryRun {
+		if err := os.Setenv("DOCKER_CONFIG", dockerPath); err != nil {
+			return fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
+		}
 	}
 	if c.IsSet("compressed-caching") {
 		flag := c.Bool("compressed-caching")
@@ -504,6 +716,33 @@ func run(c *cli.Context) error {
 
 func setDockerAuth(username, password, registry, baseImageUsername, baseImagePassword, baseImageRegistry string) error {
 	dockerConfig := docker.NewConfig()
+	credentials := registryCredentials(username, password, registry, baseImageUsername, baseImagePassword, baseImageRegistry)
+	if baseImageRegistry == "" {
+		fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
+			"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
+	}
+	// Creates docker config for both the regustries used for authentication
+	return dockerConfig.CreateDockerConfig(credentials, dockerPath)
+}
+
+// mergeDockerConfig merges the docker config override on top of the existing
+// docker config.
+func mergeDockerConfig(configOverride string) error {
+	dockerConfig, err := docker.ParseConfig([]byte(configOverride))
+	if err != nil {
+		return err
+	}
+	return dockerConfig.MergeDockerConfig(dockerPath)
+}
+
+// generateCredentials returns true if docker config credentials are created
+// from the username and password: when they are defined, or when pushing
+// without a docker config override.
+func generateCredentials(configOverride, username string, noPush bool) bool {
+	return username != "" || (len(configOverride) == 0 && !noPush)
+}
+
+func registryCredentials(username, password, registry, baseImageUsername, baseImagePassword, baseImageRegistry string) []docker.RegistryCredentials {
 	pushToRegistryCreds := docker.RegistryCredentials{
 		Registry: registry,
 		Username: username,
@@ -518,12 +757,30 @@ func setDockerAuth(username, password, registry, baseImageUsername, baseImagePas
 			Password: baseImagePassword,
 		}
 		credentials = append(credentials, pullFromRegistryCreds)
-	} else {
-		fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
-			"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
 	}
-	// Creates docker config for both the regustries used for authentication
-	return dockerConfig.CreateDockerConfig(credentials, dockerPath)
+	return credentials
+}
+
+// dryRunRegistries returns the registries the docker config would hold
+// credentials for, without writing it.
+func dryRunRegistries(configOverride, username, password, registry, baseImageUsername, baseImagePassword, baseImageRegistry string, noPush bool) ([]string, error) {
+	dockerConfig := docker.NewConfig()
+	if len(configOverride) > 0 {
+		override, err := docker.ParseConfig([]byte(configOverride))
+		if err != nil {
+			return nil, err
+		}
+		dockerConfig.Merge(override)
+	}
+	if generateCredentials(configOverride, username, noPush) {
+		credentials := registryCredentials(username, password, registry, baseImageUsername, baseImagePassword, baseImageRegistry)
+		generated := docker.NewConfig()
+		if err := generated.AddCredentials(credentials); err != nil {
+			return nil, err
+		}
+		dockerConfig.Merge(generated)
+	}
+	return dockerConfig.Registries(), nil
 }
 
 func buildRepo(registry, repo string, expandRepo bool) string {
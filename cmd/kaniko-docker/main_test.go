@@ -343,3 +343,79 @@ func TestCreateDockerConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDryRunRegistries(t *testing.T) {
+	tests := []struct {
+		name           string
+		configOverride string
+		username       string
+		password       string
+		registry       string
+		baseRegistry   string
+		noPush         bool
+		want           []string
+		wantErr        bool
+	}{
+		{
+			name:     "push registry",
+			username: "foo",
+			password: "bar",
+			registry: "https://index.docker.io/v1/",
+			want:     []string{"https://index.docker.io/v1/"},
+		},
+		{
+			name:         "push and base image registry",
+			username:     "foo",
+			password:     "bar",
+			registry:     "docker.example.com",
+			baseRegistry: "mirror.example.com",
+			want:         []string{"docker.example.com", "mirror.example.com"},
+		},
+		{
+			name:   "no push without credentials",
+			noPush: true,
+		},
+		{
+			name:           "config override",
+			configOverride: `{"auths":{"docker.example.com":{"auth":"Zm9vOmJhcg=="}},"credHelpers":{"gcr.io":"gcloud"}}`,
+			want:           []string{"docker.example.com", "gcr.io"},
+		},
+		{
+			name:           "config override and credentials",
+			configOverride: `{"auths":{"mirror.example.com":{"auth":"Zm9vOmJhcg=="}},"credHelpers":{"docker.example.com":"desktop"}}`,
+			username:       "foo",
+			password:       "bar",
+			registry:       "docker.example.com",
+			noPush:         true,
+			want:           []string{"docker.example.com", "mirror.example.com"},
+		},
+		{
+			name:           "invalid config override",
+			configOverride: `{"auths":`,
+			wantErr:        true,
+		},
+		{
+			name:     "missing password",
+			username: "foo",
+			registry: "docker.example.com",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dryRunRegistries(tt.configOverride, tt.username, tt.password, tt.registry, "foo", "bar", tt.baseRegistry, tt.noPush)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dryRunRegistries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("dryRunRegistries() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("dryRunRegistries() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
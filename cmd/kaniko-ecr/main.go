@@ -32,7 +32,6 @@ import (
 	"github.com/drone/drone-kaniko/pkg/docker"
 	"github.com/drone/drone-kaniko/pkg/utils"
 	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/crane"
 )
 
 const (
@@ -41,6 +40,7 @@ const (
 	secretKeyEnv     string = "AWS_SECRET_ACCESS_KEY"
 	ecrPublicDomain  string = "public.ecr.aws"
 	kanikoVersionEnv string = "KANIKO_VERSION"
+	regionEnv        string = "AWS_REGION"
 	sessionKeyEnv    string = "AWS_SESSION_TOKEN"
 
 	oneDotEightVersion string = "1.8.0"
@@ -71,6 +71,36 @@ func main() {
 			Value:  "Dockerfile",
 			EnvVar: "PLUGIN_DOCKERFILE",
 		},
+		cli.StringFlag{
+			Name:   "dockerfile-content",
+			Usage:  "inline dockerfile content, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CONTENT",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url",
+			Usage:  "url of a remote dockerfile, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_URL",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-token",
+			Usage:  "bearer token of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-username",
+			Usage:  "basic auth username of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_USERNAME",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-password",
+			Usage:  "basic auth password of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-checksum",
+			Usage:  "expected sha256 checksum of the remote dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CHECKSUM",
+		},
 		cli.StringFlag{
 			Name:   "docker-registry",
 			Usage:  "Docker registry for base image",
@@ -86,6 +116,11 @@ func main() {
 			Usage:  "Docker password for base image registry",
 			EnvVar: "PLUGIN_PASSWORD,PLUGIN_DOCKER_PASSWORD,PLUGIN_BASE_IMAGE_PASSWORD,DOCKER_PASSWORD",
 		},
+		cli.StringFlag{
+			Name:   "registry-credentials",
+			Usage:  "json array of additional registry credentials",
+			EnvVar: "PLUGIN_REGISTRY_CREDENTIALS",
+		},
 		cli.StringFlag{
 			Name:   "context",
 			Usage:  "build context",
@@ -114,6 +149,21 @@ func main() {
 			Usage:  "enable for semver tagging",
 			EnvVar: "PLUGIN_EXPAND_TAG",
 		},
+		cli.BoolFlag{
+			Name:   "floating-tags",
+			Usage:  "only move latest and the major and minor tags when the release is the highest of their series",
+			EnvVar: "PLUGIN_FLOATING_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "channel-tags",
+			Usage:  "tag pre-releases with the floating tags of their channel, e.g. 1.5-rc and rc",
+			EnvVar: "PLUGIN_CHANNEL_TAGS",
+		},
+		cli.StringSliceFlag{
+			Name:   "channels",
+			Usage:  "pre-release channels that get channel tags, defaults to rc, beta and alpha",
+			EnvVar: "PLUGIN_CHANNELS",
+		},
 		cli.BoolFlag{
 			Name:   "auto-tag",
 			Usage:  "enable auto generation of build tags",
@@ -124,6 +174,57 @@ func main() {
 			Usage:  "the suffix of auto build tags",
 			EnvVar: "PLUGIN_AUTO_TAG_SUFFIX",
 		},
+		cli.BoolFlag{
+			Name:   "auto-tag-branch",
+			Usage:  "auto tag builds of non-default branches with the sanitized branch name",
+			EnvVar: "PLUGIN_AUTO_TAG_BRANCH",
+		},
+		cli.BoolFlag{
+			Name:   "auto-tag-pull-request",
+			Usage:  "auto tag pull request builds with pr-<number>",
+			EnvVar: "PLUGIN_AUTO_TAG_PULL_REQUEST",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-component",
+			Usage:  "glob pattern of the component prefix of monorepo git tags",
+			EnvVar: "PLUGIN_AUTO_TAG_COMPONENT",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-scheme",
+			Usage:  "versioning scheme of auto tags and expanded tags, semver or calver",
+			Value:  "semver",
+			EnvVar: "PLUGIN_AUTO_TAG_SCHEME",
+		},
+		cli.BoolFlag{
+			Name:   "tag-remotely",
+			Usage:  "push the first tag only and create the other tags remotely with manifest-only PUTs",
+			EnvVar: "PLUGIN_TAG_REMOTELY",
+		},
+		cli.BoolFlag{
+			Name:   "verify-push",
+			Usage:  "verify every pushed tag resolves to the built digest and fail otherwise",
+			EnvVar: "PLUGIN_VERIFY_PUSH",
+		},
+		cli.BoolFlag{
+			Name:   "preflight",
+			Usage:  "check push access to the destination and cache repositories and pull access to the base images before the build",
+			EnvVar: "PLUGIN_PREFLIGHT",
+		},
+		cli.BoolFlag{
+			Name:   "preflight-warn-only",
+			Usage:  "report failed pre-flight checks without failing the build",
+			EnvVar: "PLUGIN_PREFLIGHT_WARN_ONLY",
+		},
+		cli.StringSliceFlag{
+			Name:   "immutable-tags",
+			Usage:  "tags that must not be overwritten, either all or glob patterns such as v*",
+			EnvVar: "PLUGIN_IMMUTABLE_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "dedup",
+			Usage:  "skip the build when an image with the same Dockerfile, build args, target, platform and context exists in the repository",
+			EnvVar: "PLUGIN_DEDUP",
+		},
 		cli.StringSliceFlag{
 			Name:   "args",
 			Usage:  "build args",
@@ -140,6 +241,16 @@ func main() {
 			Usage:  "plugin multiple build agrs",
 			EnvVar: "PLUGIN_MULTIPLE_BUILD_ARGS",
 		},
+		cli.StringFlag{
+			Name:   "args-file",
+			Usage:  "dotenv or json file of build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "args-from-env",
+			Usage:  "prefix of the environment variables passed as build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FROM_ENV",
+		},
 		cli.StringFlag{
 			Name:   "target",
 			Usage:  "build target",
@@ -166,6 +277,16 @@ func main() {
 			Usage:  "additional k=v labels",
 			EnvVar: "PLUGIN_CUSTOM_LABELS",
 		},
+		cli.BoolFlag{
+			Name:   "render-templates",
+			Usage:  "render label and build arg templates from the drone metadata",
+			EnvVar: "PLUGIN_RENDER_TEMPLATES",
+		},
+		cli.BoolFlag{
+			Name:   "oci-labels",
+			Usage:  "add the org.opencontainers.image labels populated from the drone metadata",
+			EnvVar: "PLUGIN_OCI_LABELS",
+		},
 		cli.StringFlag{
 			Name:   "registry",
 			Usage:  "ECR registry",
@@ -236,6 +357,16 @@ func main() {
 			Usage:  "Set this flag if you only want to build the image, without pushing to a registry",
 			EnvVar: "PLUGIN_NO_PUSH",
 		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "Print the resolved build plan as JSON without building, pushing or calling any cloud API",
+			EnvVar: "PLUGIN_DRY_RUN",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-patterns",
+			Usage:  "Name patterns of build args and environment variables whose values are masked in logs",
+			EnvVar: "PLUGIN_REDACT_PATTERNS",
+		},
 		cli.StringFlag{
 			Name:   "verbosity",
 			Usage:  "Set this flag with value as oneof <panic|fatal|error|warn|info|debug|trace> to set the logging level for kaniko. Defaults to info.",
@@ -246,6 +377,11 @@ func main() {
 			Usage:  "Allows to build with another default platform than the host, similarly to docker build --platform",
 			EnvVar: "PLUGIN_PLATFORM,PLUGIN_CUSTOM_PLATFORM",
 		},
+		cli.StringSliceFlag{
+			Name:   "platforms",
+			Usage:  "Build one image per platform and publish them as a multi-platform image index, e.g. linux/amd64,linux/arm64",
+			EnvVar: "PLUGIN_PLATFORMS",
+		},
 		cli.BoolFlag{
 			Name:   "skip-unused-stages",
 			Usage:  "build only used stages",
@@ -412,11 +548,21 @@ func main() {
 			Usage:  "Set this flag for the source tarball during push operations.",
 			EnvVar: "PLUGIN_SOURCE_TAR_PATH",
 		},
+		cli.StringFlag{
+			Name:   "source-tag",
+			Usage:  "Tag of the image to push when the source tarball or OCI layout holds several images",
+			EnvVar: "PLUGIN_SOURCE_TAG",
+		},
 		cli.BoolFlag{
 			Name:   "push-only",
 			Usage:  "Specify if the operation is push-only",
 			EnvVar: "PLUGIN_PUSH_ONLY",
 		},
+		cli.StringFlag{
+			Name:   "promote-from",
+			Usage:  "Image reference (tag or digest) to copy to the destination tags instead of building",
+			EnvVar: "PLUGIN_PROMOTE_FROM",
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -433,6 +579,7 @@ func run(c *cli.Context) error {
 	assumeRole := c.String("assume-role")
 	externalId := c.String("external-id")
 	oidcToken := c.String("oidc-token-id")
+	dryRun := c.Bool("dry-run")
 
 	// Validate flags
 	if noPush && pushOnly {
@@ -440,12 +587,42 @@ func run(c *cli.Context) error {
 	}
 
 	// Handle push-only operation
-	if pushOnly {
+	if pushOnly && !dryRun {
 		return handlePushOnly(c)
 	}
 
-	// setup docker config for azure registry and base image docker registry
-	err := setDockerAuth(
+	// the additional registry credentials are written first, so that the
+	// credentials of the plugin settings take precedence over them
+	extraCredentials, err := docker.ParseRegistryCredentials(c.String("registry-credentials"))
+	if err != nil {
+		return errors.Wrap(err, "invalid registry credentials")
+	}
+	if len(extraCredentials) > 0 && !dryRun {
+		if err := docker.NewConfig().CreateDockerConfig(extraCredentials, dockerConfigPath); err != nil {
+			return errors.Wrap(err, "failed to create docker config")
+		}
+	}
+
+	var registries []string
+	if dryRun {
+		registries, err = dryRunRegistries(
+			c.String("docker-registry"),
+			c.String("docker-username"),
+			c.String("docker-password"),
+			c.String("access-key"),
+			c.String("secret-key"),
+			registry,
+			assumeRole,
+			externalId,
+			region,
+			noPush,
+			oidcToken,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to create docker config")
+		}
+		registries = docker.AppendRegistries(registries, extraCredentials)
+	} else if err := setDockerAuth(
 		c.String("docker-registry"),
 		c.String("docker-username"),
 		c.String("docker-password"),
@@ -457,19 +634,26 @@ func run(c *cli.Context) error {
 		region,
 		noPush,
 		oidcToken,
-	)
-	if err != nil {
+	); err != nil {
 		return errors.Wrap(err, "failed to create docker config")
 	}
 
+	// the executor downloads an S3 build context with the AWS credentials of
+	// the environment, which are those of the registry
+	if !dryRun && kaniko.ContextType(c.String("context")) == kaniko.ContextS3 {
+		if err := setS3Auth(region, assumeRole, externalId, oidcToken); err != nil {
+			return errors.Wrap(err, "failed to set up S3 build context credentials")
+		}
+	}
+
 	// only create repository when pushing and create-repository is true
-	if !noPush && c.Bool("create-repository") {
+	if !dryRun && !noPush && c.Bool("create-repository") {
 		if err := createRepository(region, repo, registry, assumeRole, externalId); err != nil {
 			return err
 		}
 	}
 
-	if c.IsSet("lifecycle-policy") {
+	if !dryRun && c.IsSet("lifecycle-policy") {
 		contents, err := ioutil.ReadFile(c.String("lifecycle-policy"))
 		if err != nil {
 			logrus.Fatal(err)
@@ -479,7 +663,7 @@ func run(c *cli.Context) error {
 		}
 	}
 
-	if c.IsSet("repository-policy") {
+	if !dryRun && c.IsSet("repository-policy") {
 		contents, err := ioutil.ReadFile(c.String("repository-policy"))
 		if err != nil {
 			logrus.Fatal(err)
@@ -489,31 +673,73 @@ func run(c *cli.Context) error {
 		}
 	}
 
+	plugin := newPlugin(c)
+	plugin.Registries = registries
+	if !dryRun {
+		plugin, err = withRegistryKeychain(plugin, registry, func() (string, string, error) {
+			return getECRCredentials(region, registry, assumeRole, externalId, c.String("access-key"), c.String("secret-key"), oidcToken)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return plugin.Exec()
+}
+
+// newPlugin returns the plugin of the settings. The build and the push-only
+// operation both use it, so that they honour the same settings.
+func newPlugin(c *cli.Context) kaniko.Plugin {
 	plugin := kaniko.Plugin{
 		Build: kaniko.Build{
 			DroneCommitRef:              c.String("drone-commit-ref"),
 			DroneRepoBranch:             c.String("drone-repo-branch"),
 			Dockerfile:                  c.String("dockerfile"),
+			DockerfileContent:           c.String("dockerfile-content"),
+			DockerfileURL:               c.String("dockerfile-url"),
+			DockerfileToken:             c.String("dockerfile-url-token"),
+			DockerfileUsername:          c.String("dockerfile-url-username"),
+			DockerfilePassword:          c.String("dockerfile-url-password"),
+			DockerfileChecksum:          c.String("dockerfile-checksum"),
 			Context:                     c.String("context"),
 			Tags:                        c.StringSlice("tags"),
 			AutoTag:                     c.Bool("auto-tag"),
 			AutoTagSuffix:               c.String("auto-tag-suffix"),
+			AutoTagBranch:               c.Bool("auto-tag-branch"),
+			AutoTagPullRequest:          c.Bool("auto-tag-pull-request"),
+			AutoTagComponent:            c.String("auto-tag-component"),
+			AutoTagScheme:               c.String("auto-tag-scheme"),
 			ExpandTag:                   c.Bool("expand-tag"),
+			FloatingTags:                c.Bool("floating-tags"),
+			ChannelTags:                 c.Bool("channel-tags"),
+			Channels:                    c.StringSlice("channels"),
+			TagRemotely:                 c.Bool("tag-remotely"),
+			VerifyPush:                  c.Bool("verify-push"),
+			Preflight:                   c.Bool("preflight"),
+			PreflightWarnOnly:           c.Bool("preflight-warn-only"),
+			ImmutableTags:               c.StringSlice("immutable-tags"),
+			Dedup:                       c.Bool("dedup"),
 			Args:                        c.StringSlice("args"),
 			ArgsNew:                     c.Generic("args-new").(*utils.CustomStringSliceFlag).GetValue(),
 			IsMultipleBuildArgs:         c.Bool("plugin-multiple-build-agrs"),
+			ArgsFile:                    c.String("args-file"),
+			ArgsFromEnv:                 c.String("args-from-env"),
 			Target:                      c.String("target"),
 			Repo:                        fmt.Sprintf("%s/%s", c.String("registry"), c.String("repo")),
 			Mirrors:                     c.StringSlice("registry-mirrors"),
 			Labels:                      c.StringSlice("custom-labels"),
+			RenderTemplates:             c.Bool("render-templates"),
+			OCILabels:                   c.Bool("oci-labels"),
 			SnapshotMode:                c.String("snapshot-mode"),
 			EnableCache:                 c.Bool("enable-cache"),
 			CacheRepo:                   fmt.Sprintf("%s/%s", c.String("registry"), c.String("cache-repo")),
 			CacheTTL:                    c.Int("cache-ttl"),
 			DigestFile:                  defaultDigestFile,
-			NoPush:                      noPush,
+			NoPush:                      c.Bool("no-push"),
+			DryRun:                      c.Bool("dry-run"),
+			RedactPatterns:              c.StringSlice("redact-patterns"),
 			Verbosity:                   c.String("verbosity"),
 			CustomPlatform:              c.String("platform"),
+			Platforms:                   c.StringSlice("platforms"),
 			SkipUnusedStages:            c.Bool("skip-unused-stages"),
 			CacheDir:                    c.String("cache-dir"),
 			CacheCopyLayers:             c.Bool("cache-copy-layers"),
@@ -547,7 +773,9 @@ func run(c *cli.Context) error {
 			ImageDownloadRetry:          c.Int("image-download-retry"),
 			TarPath:                     c.String("tar-path"),
 			SourceTarPath:               c.String("source-tar-path"),
+			SourceTag:                   c.String("source-tag"),
 			PushOnly:                    c.Bool("push-only"),
+			PromoteFrom:                 c.String("promote-from"),
 		},
 		Artifact: kaniko.Artifact{
 			Tags:         c.StringSlice("tags"),
@@ -565,12 +793,45 @@ func run(c *cli.Context) error {
 		flag := c.Bool("ignore-var-run")
 		plugin.Build.IgnoreVarRun = &flag
 	}
-	return plugin.Exec()
+	return plugin
+}
+
+// withRegistryKeychain returns the plugin with a keychain for the registry
+// calls the plugin makes itself, e.g. to verify the push or to tag remotely.
+// The docker config does not hold ECR credentials with kaniko 1.8 and later,
+// so ECR is authenticated with the credentials, the other registries are
+// resolved from the docker config.
+func withRegistryKeychain(plugin kaniko.Plugin, registry string, credentials func() (string, string, error)) (kaniko.Plugin, error) {
+	if !plugin.Build.CallsRegistry() {
+		return plugin, nil
+	}
+	username, password, err := credentials()
+	if err != nil {
+		return plugin, err
+	}
+	plugin.Keychain = kaniko.RegistryKeychain(registry, &authn.Basic{Username: username, Password: password})
+	if err := os.Setenv("DOCKER_CONFIG", dockerConfigPath); err != nil {
+		return plugin, fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
+	}
+	return plugin, nil
 }
 
 func setDockerAuth(dockerRegistry, dockerUsername, dockerPassword, accessKey, secretKey,
 	registry, assumeRole, externalId, region string, noPush bool, oidcToken string) error {
-	dockerConfig := docker.NewConfig()
+	credentials, err := registryCredentials(dockerRegistry, dockerUsername, dockerPassword, accessKey, secretKey,
+		registry, assumeRole, externalId, region, noPush, oidcToken, false)
+	if err != nil {
+		return err
+	}
+	return docker.NewConfig().CreateDockerConfig(credentials, dockerConfigPath)
+}
+
+// registryCredentials returns the credentials of the docker config and sets
+// up the AWS credentials of the executor. In dry runs no role is assumed and
+// no environment variable is set, so only the registries of the returned
+// credentials are meaningful.
+func registryCredentials(dockerRegistry, dockerUsername, dockerPassword, accessKey, secretKey,
+	registry, assumeRole, externalId, region string, noPush bool, oidcToken string, dryRun bool) ([]docker.RegistryCredentials, error) {
 	credentials := []docker.RegistryCredentials{}
 	// set docker credentials for base image registry
 	if dockerRegistry != "" {
@@ -580,68 +841,116 @@ func setDockerAuth(dockerRegistry, dockerUsername, dockerPassword, accessKey, se
 			Password: dockerPassword,
 		}
 		credentials = append(credentials, pullFromRegistryCreds)
-	} else {
+	} else if !dryRun {
 		fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
 			"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
 	}
 
+	// kaniko-executor >=1.8.0 does not require additional cred helper logic for ECR,
+	// as it discovers ECR repositories automatically and acts accordingly.
+	ecrCredHelpers := []docker.RegistryCredentials{
+		{Registry: ecrPublicDomain, CredHelper: "ecr-login"},
+		{Registry: registry, CredHelper: "ecr-login"},
+	}
+
 	if assumeRole != "" && oidcToken != "" {
-		oidcAccessKey, oidcSecretKey, oidcSessionKey, err := getOidcCreds(oidcToken, assumeRole)
-		if err != nil {
-			return err
-		}
+		if !dryRun {
+			oidcAccessKey, oidcSecretKey, oidcSessionKey, err := getOidcCreds(oidcToken, assumeRole)
+			if err != nil {
+				return nil, err
+			}
 
-		_ = os.Setenv(accessKeyEnv, oidcAccessKey)
-		_ = os.Setenv(secretKeyEnv, oidcSecretKey)
-		_ = os.Setenv(sessionKeyEnv, oidcSessionKey)
+			_ = os.Setenv(accessKeyEnv, oidcAccessKey)
+			_ = os.Setenv(secretKeyEnv, oidcSecretKey)
+			_ = os.Setenv(sessionKeyEnv, oidcSessionKey)
+		}
 
-		// kaniko-executor >=1.8.0 does not require additional cred helper logic for ECR,
-		// as it discovers ECR repositories automatically and acts accordingly.
 		if isKanikoVersionBelowOneDotEight(os.Getenv(kanikoVersionEnv)) {
-			dockerConfig.SetCredHelper(ecrPublicDomain, "ecr-login")
-			dockerConfig.SetCredHelper(registry, "ecr-login")
+			credentials = append(credentials, ecrCredHelpers...)
 		}
 
 	} else if assumeRole != "" {
-		var err error
-		username, password, registry, err := getAssumeRoleCreds(region, assumeRole, externalId, "")
-		if err != nil {
-			return err
-		}
-		pushToRegistryCreds := docker.RegistryCredentials{
-			Registry: registry,
-			Username: username,
-			Password: password,
+		pushToRegistryCreds := docker.RegistryCredentials{Registry: registry}
+		if !dryRun {
+			username, password, registry, err := getAssumeRoleCreds(region, assumeRole, externalId, "")
+			if err != nil {
+				return nil, err
+			}
+			pushToRegistryCreds = docker.RegistryCredentials{
+				Registry: registry,
+				Username: username,
+				Password: password,
+			}
 		}
 		credentials = append(credentials, pushToRegistryCreds)
 
 	} else if !noPush || accessKey != "" {
 		// only setup auth when pushing or credentials are defined
 		if registry == "" {
-			return fmt.Errorf("registry must be specified")
+			return nil, fmt.Errorf("registry must be specified")
 		}
 
 		// If IAM role is used, access key & secret key are not required
-		if accessKey != "" && secretKey != "" {
+		if accessKey != "" && secretKey != "" && !dryRun {
 			err := os.Setenv(accessKeyEnv, accessKey)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", accessKeyEnv))
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", accessKeyEnv))
 			}
 
 			err = os.Setenv(secretKeyEnv, secretKey)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", secretKeyEnv))
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", secretKeyEnv))
 			}
 		}
 
-		// kaniko-executor >=1.8.0 does not require additional cred helper logic for ECR,
-		// as it discovers ECR repositories automatically and acts accordingly.
 		if isKanikoVersionBelowOneDotEight(os.Getenv(kanikoVersionEnv)) {
-			dockerConfig.SetCredHelper(ecrPublicDomain, "ecr-login")
-			dockerConfig.SetCredHelper(registry, "ecr-login")
+			credentials = append(credentials, ecrCredHelpers...)
 		}
 	}
-	return dockerConfig.CreateDockerConfig(credentials, dockerConfigPath)
+	return credentials, nil
+}
+
+// setS3Auth exports the region and, when a role is assumed without OIDC, the
+// temporary credentials of the role. Static keys and the credentials of an
+// OIDC role are already exported by setDockerAuth.
+func setS3Auth(region, assumeRole, externalId, oidcToken string) error {
+	if os.Getenv(regionEnv) == "" && region != "" {
+		if err := os.Setenv(regionEnv, region); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to set %s environment variable", regionEnv))
+		}
+	}
+	if assumeRole == "" || oidcToken != "" {
+		return nil
+	}
+
+	sess, err := session.NewSession(&awsv1.Config{Region: &region})
+	if err != nil {
+		return errors.Wrap(err, "failed to create aws session")
+	}
+	creds, err := stscreds.NewCredentials(sess, assumeRole, func(p *stscreds.AssumeRoleProvider) {
+		if externalId != "" {
+			p.ExternalID = &externalId
+		}
+	}).Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to assume role")
+	}
+	_ = os.Setenv(accessKeyEnv, creds.AccessKeyID)
+	_ = os.Setenv(secretKeyEnv, creds.SecretAccessKey)
+	_ = os.Setenv(sessionKeyEnv, creds.SessionToken)
+	return nil
+}
+
+// dryRunRegistries returns the registries the docker config of a build
+// holds credentials for, without calling AWS.
+func dryRunRegistries(dockerRegistry, dockerUsername, dockerPassword, accessKey, secretKey,
+	registry, assumeRole, externalId, region string, noPush bool, oidcToken string) ([]string, error) {
+	credentials, err := registryCredentials(dockerRegistry, dockerUsername, dockerPassword, accessKey, secretKey,
+		registry, assumeRole, externalId, region, noPush, oidcToken, true)
+	if err != nil {
+		return nil, err
+	}
+	return docker.AppendRegistries(nil, credentials), nil
 }
 
 func createRepository(region, repo, registry, assumeRole, externalId string) error {
@@ -959,12 +1268,6 @@ func handlePushOnly(c *cli.Context) error {
 		return fmt.Errorf("repository and registry must be specified for push-only operation")
 	}
 
-	// Load the image from the tarball
-	img, err := crane.Load(sourceTarPath)
-	if err != nil {
-		return fmt.Errorf("failed to load image from tarball: %v", err)
-	}
-
 	// Get ECR credentials using the common function
 	username, password, err := getECRCredentials(
 		c.String("region"),
@@ -979,27 +1282,13 @@ func handlePushOnly(c *cli.Context) error {
 		return err
 	}
 
-	// Setup crane auth
-	opts := []crane.Option{
-		crane.WithAuth(&authn.Basic{
-			Username: username,
-			Password: password,
-		}),
-	}
-
-	// Push for each tag
-	tags := c.StringSlice("tags")
-	if len(tags) == 0 {
-		tags = []string{"latest"}
-	}
-
-	for _, tag := range tags {
-		dest := fmt.Sprintf("%s/%s:%s", registry, repo, tag)
-		if err := crane.Push(img, dest, opts...); err != nil {
-			return fmt.Errorf("failed to push image to %s: %v", dest, err)
-		}
-		fmt.Printf("Successfully pushed image to %s\n", dest)
+	plugin := newPlugin(c)
+	plugin.Build.PushOnly = true
+	plugin, err = withRegistryKeychain(plugin, registry, func() (string, string, error) {
+		return username, password, nil
+	})
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return plugin.Push()
 }
@@ -2,12 +2,16 @@ package main
 
 import (
 	"encoding/base64"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
 
+	kaniko "github.com/drone/drone-kaniko"
 	"github.com/drone/drone-kaniko/pkg/docker"
 	"github.com/drone/drone-kaniko/pkg/utils"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/stretchr/testify/assert"
 	"github.com/urfave/cli"
 )
@@ -207,3 +211,113 @@ func TestECRBuildArgsProcessing(t *testing.T) {
 		})
 	}
 }
+
+func TestSetS3AuthRegion(t *testing.T) {
+	t.Setenv(regionEnv, "")
+	assert.NoError(t, setS3Auth("eu-west-1", "", "", ""))
+	assert.Equal(t, "eu-west-1", os.Getenv(regionEnv))
+
+	t.Setenv(regionEnv, "us-east-1")
+	assert.NoError(t, setS3Auth("eu-west-1", "", "", ""))
+	assert.Equal(t, "us-east-1", os.Getenv(regionEnv))
+}
+
+func TestWithRegistryKeychain(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", "")
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	tests := []struct {
+		name  string
+		build kaniko.Build
+		want  bool
+	}{
+		{name: "build only", build: kaniko.Build{}},
+		{name: "verify push", build: kaniko.Build{VerifyPush: true}, want: true},
+		{name: "tag remotely", build: kaniko.Build{TagRemotely: true}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			plugin, err := withRegistryKeychain(kaniko.Plugin{Build: tt.build}, registry, func() (string, string, error) {
+				called = true
+				return "AWS", "token", nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, called)
+			assert.Equal(t, tt.want, plugin.Keychain != nil)
+			if tt.want {
+				auth, err := plugin.Keychain.Resolve(mustRegistry(t, registry))
+				assert.NoError(t, err)
+				assert.Equal(t, &authn.Basic{Username: "AWS", Password: "token"}, auth)
+			}
+		})
+	}
+
+	_, err := withRegistryKeychain(kaniko.Plugin{Build: kaniko.Build{VerifyPush: true}}, registry, func() (string, string, error) {
+		return "", "", errors.New("no credentials")
+	})
+	assert.Error(t, err)
+}
+
+func mustRegistry(t *testing.T, registry string) name.Registry {
+	r, err := name.NewRegistry(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestDryRunRegistries(t *testing.T) {
+	t.Setenv(accessKeyEnv, "")
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	tests := []struct {
+		name          string
+		kanikoVersion string
+		accessKey     string
+		registry      string
+		assumeRole    string
+		noPush        bool
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name:          "assume role",
+			kanikoVersion: "1.9.0",
+			registry:      registry,
+			assumeRole:    "arn:aws:iam::123456789012:role/push",
+			want:          []string{"docker.io", registry},
+		},
+		{
+			name:          "access key with cred helpers",
+			kanikoVersion: "1.7.0",
+			accessKey:     "access-key",
+			registry:      registry,
+			want:          []string{"docker.io", ecrPublicDomain, registry},
+		},
+		{
+			name:          "no push",
+			kanikoVersion: "1.9.0",
+			registry:      registry,
+			noPush:        true,
+			want:          []string{"docker.io"},
+		},
+		{
+			name:          "missing registry",
+			kanikoVersion: "1.9.0",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(kanikoVersionEnv, tt.kanikoVersion)
+			got, err := dryRunRegistries("docker.io", "user", "pass", tt.accessKey, "secret-key",
+				tt.registry, tt.assumeRole, "", "us-east-1", tt.noPush, "")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Empty(t, os.Getenv(accessKeyEnv))
+		})
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/drone/drone-kaniko/pkg/artifact"
 	"github.com/drone/drone-kaniko/pkg/docker"
 	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/drone/drone-kaniko/pkg/utils"
 
 )
@@ -55,6 +54,36 @@ func main() {
 			Value:  "Dockerfile",
 			EnvVar: "PLUGIN_DOCKERFILE",
 		},
+		cli.StringFlag{
+			Name:   "dockerfile-content",
+			Usage:  "inline dockerfile content, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CONTENT",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url",
+			Usage:  "url of a remote dockerfile, used instead of the dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_URL",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-token",
+			Usage:  "bearer token of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-username",
+			Usage:  "basic auth username of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_USERNAME",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-url-password",
+			Usage:  "basic auth password of the remote dockerfile url",
+			EnvVar: "PLUGIN_DOCKERFILE_URL_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:   "dockerfile-checksum",
+			Usage:  "expected sha256 checksum of the remote dockerfile",
+			EnvVar: "PLUGIN_DOCKERFILE_CHECKSUM",
+		},
 		cli.StringFlag{
 			Name:   "context",
 			Usage:  "build context",
@@ -83,6 +112,21 @@ func main() {
 			Usage:  "enable for semver tagging",
 			EnvVar: "PLUGIN_EXPAND_TAG",
 		},
+		cli.BoolFlag{
+			Name:   "floating-tags",
+			Usage:  "only move latest and the major and minor tags when the release is the highest of their series",
+			EnvVar: "PLUGIN_FLOATING_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "channel-tags",
+			Usage:  "tag pre-releases with the floating tags of their channel, e.g. 1.5-rc and rc",
+			EnvVar: "PLUGIN_CHANNEL_TAGS",
+		},
+		cli.StringSliceFlag{
+			Name:   "channels",
+			Usage:  "pre-release channels that get channel tags, defaults to rc, beta and alpha",
+			EnvVar: "PLUGIN_CHANNELS",
+		},
 		cli.BoolFlag{
 			Name:   "auto-tag",
 			Usage:  "enable auto generation of build tags",
@@ -93,6 +137,57 @@ func main() {
 			Usage:  "the suffix of auto build tags",
 			EnvVar: "PLUGIN_AUTO_TAG_SUFFIX",
 		},
+		cli.BoolFlag{
+			Name:   "auto-tag-branch",
+			Usage:  "auto tag builds of non-default branches with the sanitized branch name",
+			EnvVar: "PLUGIN_AUTO_TAG_BRANCH",
+		},
+		cli.BoolFlag{
+			Name:   "auto-tag-pull-request",
+			Usage:  "auto tag pull request builds with pr-<number>",
+			EnvVar: "PLUGIN_AUTO_TAG_PULL_REQUEST",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-component",
+			Usage:  "glob pattern of the component prefix of monorepo git tags",
+			EnvVar: "PLUGIN_AUTO_TAG_COMPONENT",
+		},
+		cli.StringFlag{
+			Name:   "auto-tag-scheme",
+			Usage:  "versioning scheme of auto tags and expanded tags, semver or calver",
+			Value:  "semver",
+			EnvVar: "PLUGIN_AUTO_TAG_SCHEME",
+		},
+		cli.BoolFlag{
+			Name:   "tag-remotely",
+			Usage:  "push the first tag only and create the other tags remotely with manifest-only PUTs",
+			EnvVar: "PLUGIN_TAG_REMOTELY",
+		},
+		cli.BoolFlag{
+			Name:   "verify-push",
+			Usage:  "verify every pushed tag resolves to the built digest and fail otherwise",
+			EnvVar: "PLUGIN_VERIFY_PUSH",
+		},
+		cli.BoolFlag{
+			Name:   "preflight",
+			Usage:  "check push access to the destination and cache repositories and pull access to the base images before the build",
+			EnvVar: "PLUGIN_PREFLIGHT",
+		},
+		cli.BoolFlag{
+			Name:   "preflight-warn-only",
+			Usage:  "report failed pre-flight checks without failing the build",
+			EnvVar: "PLUGIN_PREFLIGHT_WARN_ONLY",
+		},
+		cli.StringSliceFlag{
+			Name:   "immutable-tags",
+			Usage:  "tags that must not be overwritten, either all or glob patterns such as v*",
+			EnvVar: "PLUGIN_IMMUTABLE_TAGS",
+		},
+		cli.BoolFlag{
+			Name:   "dedup",
+			Usage:  "skip the build when an image with the same Dockerfile, build args, target, platform and context exists in the repository",
+			EnvVar: "PLUGIN_DEDUP",
+		},
 		cli.StringSliceFlag{
 			Name:   "args",
 			Usage:  "build args",
@@ -109,6 +204,16 @@ func main() {
 			Usage:  "plugin multiple build agrs",
 			EnvVar: "PLUGIN_MULTIPLE_BUILD_ARGS",
 		},
+		cli.StringFlag{
+			Name:   "args-file",
+			Usage:  "dotenv or json file of build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "args-from-env",
+			Usage:  "prefix of the environment variables passed as build args",
+			EnvVar: "PLUGIN_BUILD_ARGS_FROM_ENV",
+		},
 		cli.StringFlag{
 			Name:   "target",
 			Usage:  "build target",
@@ -124,6 +229,16 @@ func main() {
 			Usage:  "additional k=v labels",
 			EnvVar: "PLUGIN_CUSTOM_LABELS",
 		},
+		cli.BoolFlag{
+			Name:   "render-templates",
+			Usage:  "render label and build arg templates from the drone metadata",
+			EnvVar: "PLUGIN_RENDER_TEMPLATES",
+		},
+		cli.BoolFlag{
+			Name:   "oci-labels",
+			Usage:  "add the org.opencontainers.image labels populated from the drone metadata",
+			EnvVar: "PLUGIN_OCI_LABELS",
+		},
 		cli.StringFlag{
 			Name:   "registry",
 			Usage:  "gar registry",
@@ -144,6 +259,11 @@ func main() {
 			Usage:  "Docker registry for base image registry",
 			EnvVar: "PLUGIN_DOCKER_REGISTRY,PLUGIN_BASE_IMAGE_REGISTRY,DOCKER_REGISTRY",
 		},
+		cli.StringFlag{
+			Name:   "registry-credentials",
+			Usage:  "json array of additional registry credentials",
+			EnvVar: "PLUGIN_REGISTRY_CREDENTIALS",
+		},
 		cli.StringSliceFlag{
 			Name:   "registry-mirrors",
 			Usage:  "docker registry mirrors",
@@ -184,16 +304,36 @@ func main() {
 			Usage:  "Set this flag if you only want to build the image, without pushing to a registry",
 			EnvVar: "PLUGIN_NO_PUSH",
 		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "Print the resolved build plan as JSON without building, pushing or calling any cloud API",
+			EnvVar: "PLUGIN_DRY_RUN",
+		},
+		cli.StringSliceFlag{
+			Name:   "redact-patterns",
+			Usage:  "Name patterns of build args and environment variables whose values are masked in logs",
+			EnvVar: "PLUGIN_REDACT_PATTERNS",
+		},
 		cli.BoolFlag{
 			Name:   "push-only",
 			Usage:  "Set this flag if you only want to push a pre-built image from a tarball",
 			EnvVar: "PLUGIN_PUSH_ONLY",
 		},
+		cli.StringFlag{
+			Name:   "promote-from",
+			Usage:  "Image reference (tag or digest) to copy to the destination tags instead of building",
+			EnvVar: "PLUGIN_PROMOTE_FROM",
+		},
 		cli.StringFlag{
 			Name:   "source-tar-path",
 			Usage:  "Path to the local tarball to be pushed when push-only is set",
 			EnvVar: "PLUGIN_SOURCE_TAR_PATH",
 		},
+		cli.StringFlag{
+			Name:   "source-tag",
+			Usage:  "Tag of the image to push when the source tarball or OCI layout holds several images",
+			EnvVar: "PLUGIN_SOURCE_TAG",
+		},
 		cli.StringFlag{
 			Name:   "tar-path",
 			Usage:  "Set this flag to save the image as a tarball at path",
@@ -209,6 +349,11 @@ func main() {
 			Usage:  "Allows to build with another default platform than the host, similarly to docker build --platform",
 			EnvVar: "PLUGIN_PLATFORM,PLUGIN_CUSTOM_PLATFORM",
 		},
+		cli.StringSliceFlag{
+			Name:   "platforms",
+			Usage:  "Build one image per platform and publish them as a multi-platform image index, e.g. linux/amd64,linux/arm64",
+			EnvVar: "PLUGIN_PLATFORMS",
+		},
 		cli.BoolFlag{
 			Name:   "skip-unused-stages",
 			Usage:  "build only used stages",
@@ -368,64 +513,113 @@ func main() {
 }
 
 func run(c *cli.Context) error {
+	dryRun := c.Bool("dry-run")
+
 	// Check if this is a push-only operation
-	if c.Bool("push-only") {
+	if c.Bool("push-only") && !dryRun {
 		return handlePushOnly(c)
 	}
 
-	noPush := c.Bool("no-push")
+	// the additional registry credentials are written first, so that the
+	// credentials of the plugin settings take precedence over them
+	extraCredentials, err := docker.ParseRegistryCredentials(c.String("registry-credentials"))
+	if err != nil {
+		return errors.Wrap(err, "invalid registry credentials")
+	}
+	if len(extraCredentials) > 0 && !dryRun {
+		if err := docker.NewConfig().CreateDockerConfig(extraCredentials, dockerConfigPath); err != nil {
+			return errors.Wrap(err, "failed to create docker config")
+		}
+	}
+
 	jsonKey := c.String("json-key")
 	// JSON key may not be set in the following cases:
 	// 1. Image does not need to be pushed to GAR.
 	// 2. Workload identity is set on GKE in which pod will inherit the credentials via service account.
-	if jsonKey != "" {
-		if err := setupGARAuth(jsonKey); err != nil {
-			return err
-		}
+	registries, err := setupAuth(
+		jsonKey,
+		c.String("registry"),
+		c.String("base-image-username"),
+		c.String("base-image-password"),
+		c.String("base-image-registry"),
+		dryRun,
+	)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		registries = docker.AppendRegistries(registries, extraCredentials)
+	}
 
-		// setup docker config only when base image registry is specified
-		if c.String("base-image-registry") != "" {
-			if err := setDockerAuth(
-				c.String("base-image-username"),
-				c.String("base-image-password"),
-				c.String("base-image-registry"),
-			); err != nil {
-				return errors.Wrap(err, "failed to create docker config")
-			}
-		} else {
-			fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
-				"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
+	plugin := newPlugin(c)
+	plugin.Registries = registries
+	if !dryRun {
+		if plugin, err = withRegistryKeychain(plugin, c.String("registry"), jsonKey); err != nil {
+			return err
 		}
 	}
+	return plugin.Exec()
+}
 
+// newPlugin returns the plugin of the settings. The build and the push-only
+// operation both use it, so that they honour the same settings.
+func newPlugin(c *cli.Context) kaniko.Plugin {
 	plugin := kaniko.Plugin{
 		Build: kaniko.Build{
 			DroneCommitRef:              c.String("drone-commit-ref"),
 			DroneRepoBranch:             c.String("drone-repo-branch"),
 			Dockerfile:                  c.String("dockerfile"),
+			DockerfileContent:           c.String("dockerfile-content"),
+			DockerfileURL:               c.String("dockerfile-url"),
+			DockerfileToken:             c.String("dockerfile-url-token"),
+			DockerfileUsername:          c.String("dockerfile-url-username"),
+			DockerfilePassword:          c.String("dockerfile-url-password"),
+			DockerfileChecksum:          c.String("dockerfile-checksum"),
 			Context:                     c.String("context"),
 			Tags:                        c.StringSlice("tags"),
 			AutoTag:                     c.Bool("auto-tag"),
 			AutoTagSuffix:               c.String("auto-tag-suffix"),
+			AutoTagBranch:               c.Bool("auto-tag-branch"),
+			AutoTagPullRequest:          c.Bool("auto-tag-pull-request"),
+			AutoTagComponent:            c.String("auto-tag-component"),
+			AutoTagScheme:               c.String("auto-tag-scheme"),
 			ExpandTag:                   c.Bool("expand-tag"),
+			FloatingTags:                c.Bool("floating-tags"),
+			ChannelTags:                 c.Bool("channel-tags"),
+			Channels:                    c.StringSlice("channels"),
+			TagRemotely:                 c.Bool("tag-remotely"),
+			VerifyPush:                  c.Bool("verify-push"),
+			Preflight:                   c.Bool("preflight"),
+			PreflightWarnOnly:           c.Bool("preflight-warn-only"),
+			ImmutableTags:               c.StringSlice("immutable-tags"),
+			Dedup:                       c.Bool("dedup"),
 			Args:                        c.StringSlice("args"),
 			ArgsNew:                     c.Generic("args-new").(*utils.CustomStringSliceFlag).GetValue(),
 			IsMultipleBuildArgs:         c.Bool("plugin-multiple-build-agrs"),
+			ArgsFile:                    c.String("args-file"),
+			ArgsFromEnv:                 c.String("args-from-env"),
 			Target:                      c.String("target"),
 			Repo:                        fmt.Sprintf("%s/%s", c.String("registry"), c.String("repo")),
 			Mirrors:                     c.StringSlice("registry-mirrors"),
 			Labels:                      c.StringSlice("custom-labels"),
+			RenderTemplates:             c.Bool("render-templates"),
+			OCILabels:                   c.Bool("oci-labels"),
 			SnapshotMode:                c.String("snapshot-mode"),
 			EnableCache:                 c.Bool("enable-cache"),
 			CacheRepo:                   fmt.Sprintf("%s/%s", c.String("registry"), c.String("cache-repo")),
 			CacheTTL:                    c.Int("cache-ttl"),
 			DigestFile:                  defaultDigestFile,
-			NoPush:                      noPush,
+			NoPush:                      c.Bool("no-push"),
+			DryRun:                      c.Bool("dry-run"),
+			RedactPatterns:              c.StringSlice("redact-patterns"),
 			PushOnly:                    c.Bool("push-only"),
+			PromoteFrom:                 c.String("promote-from"),
 			SourceTarPath:               c.String("source-tar-path"),
+			SourceTag:                   c.String("source-tag"),
 			TarPath:                     c.String("tar-path"),
 			Verbosity:                   c.String("verbosity"),
 			CustomPlatform:              c.String("platform"),
+			Platforms:                   c.StringSlice("platforms"),
 			SkipUnusedStages:            c.Bool("skip-unused-stages"),
 			CacheDir:                    c.String("cache-dir"),
 			CacheCopyLayers:             c.Bool("cache-copy-layers"),
@@ -474,19 +668,61 @@ func run(c *cli.Context) error {
 		flag := c.Bool("ignore-var-run")
 		plugin.Build.IgnoreVarRun = &flag
 	}
-	return plugin.Exec()
+	return plugin
 }
 
-func setDockerAuth(dockerUsername, dockerPassword, dockerRegistry string) error {
-	dockerConfig := docker.NewConfig()
-	dockerRegistryCreds := docker.RegistryCredentials{
-		Registry: dockerRegistry,
-		Username: dockerUsername,
-		Password: dockerPassword,
+// withRegistryKeychain returns the plugin with a keychain for the registry
+// calls the plugin makes itself, e.g. to verify the push or to tag remotely.
+// The docker config does not hold GAR credentials, which the default keychain
+// does not read from GOOGLE_APPLICATION_CREDENTIALS either, so GAR is
+// authenticated with the JSON key, the other registries are resolved from
+// the docker config.
+func withRegistryKeychain(plugin kaniko.Plugin, registry, jsonKey string) (kaniko.Plugin, error) {
+	if !plugin.Build.CallsRegistry() {
+		return plugin, nil
 	}
-	credentials := []docker.RegistryCredentials{dockerRegistryCreds}
+	if jsonKey != "" {
+		plugin.Keychain = kaniko.RegistryKeychain(registry, &authn.Basic{Username: "_json_key", Password: jsonKey})
+	}
+	if err := os.Setenv("DOCKER_CONFIG", dockerConfigPath); err != nil {
+		return plugin, fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
+	}
+	return plugin, nil
+}
 
-	return dockerConfig.CreateDockerConfig(credentials, dockerConfigPath)
+// setupAuth sets up the credentials of the json key and the docker config of
+// the base image registry, and returns the registries they are for. Dry runs
+// only return the registries.
+func setupAuth(jsonKey, registry, baseImageUsername, baseImagePassword, baseImageRegistry string, dryRun bool) ([]string, error) {
+	if jsonKey == "" {
+		return nil, nil
+	}
+	if !dryRun {
+		if err := setupGARAuth(jsonKey); err != nil {
+			return nil, err
+		}
+	}
+	registries := []string{registry}
+
+	// setup docker config only when base image registry is specified
+	if baseImageRegistry == "" {
+		if !dryRun {
+			fmt.Println("\033[33mTo ensure consistent and reliable pipeline execution, we recommend setting up a Base Image Connector.\033[0m\n" +
+				"\033[33mWhile optional at this time, configuring it helps prevent failures caused by Docker Hub's rate limits.\033[0m")
+		}
+		return registries, nil
+	}
+	credentials := []docker.RegistryCredentials{{
+		Registry: baseImageRegistry,
+		Username: baseImageUsername,
+		Password: baseImagePassword,
+	}}
+	if !dryRun {
+		if err := docker.NewConfig().CreateDockerConfig(credentials, dockerConfigPath); err != nil {
+			return nil, errors.Wrap(err, "failed to create docker config")
+		}
+	}
+	return docker.AppendRegistries(registries, credentials), nil
 }
 
 func setupGARAuth(jsonKey string) error {
@@ -519,9 +755,6 @@ func handlePushOnly(c *cli.Context) error {
 		return fmt.Errorf("repository and registry must be specified for push-only operation")
 	}
 
-	// Authentication options for crane
-	var opts []crane.Option
-
 	// Setup GAR authentication
 	jsonKey := c.String("json-key")
 	if jsonKey != "" {
@@ -578,36 +811,15 @@ func handlePushOnly(c *cli.Context) error {
 		if err := os.Setenv("DOCKER_CONFIG", dockerConfigDir); err != nil {
 			return fmt.Errorf("failed to set DOCKER_CONFIG environment variable: %v", err)
 		}
-
-		// Set up crane to use basic auth with docker config
-		opts = append(opts, crane.WithAuthFromKeychain(authn.DefaultKeychain))
 	} else {
 		logrus.Warn("No JSON key provided, authentication may fail if not running with workload identity")
 	}
 
-	// Load the image from the tarball
-	logrus.Infof("Loading image from tarball: %s", sourceTarPath)
-	img, err := crane.Load(sourceTarPath)
+	plugin := newPlugin(c)
+	plugin.Build.PushOnly = true
+	plugin, err := withRegistryKeychain(plugin, registry, jsonKey)
 	if err != nil {
-		return fmt.Errorf("failed to load image from tarball: %v", err)
-	}
-
-	// Push for each tag
-	tags := c.StringSlice("tags")
-	if len(tags) == 0 {
-		tags = []string{"latest"}
-	}
-
-	for _, tag := range tags {
-		dest := fmt.Sprintf("%s/%s:%s", registry, repo, tag)
-		logrus.Infof("Pushing image to: %s", dest)
-
-		if err := crane.Push(img, dest, opts...); err != nil {
-			return fmt.Errorf("failed to push image to %s: %v", dest, err)
-		}
-
-		logrus.Infof("Successfully pushed image to %s", dest)
+		return err
 	}
-
-	return nil
+	return plugin.Push()
 }
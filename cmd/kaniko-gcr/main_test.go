@@ -3,9 +3,12 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"reflect"
 	"testing"
 
+	kaniko "github.com/drone/drone-kaniko"
 	"github.com/drone/drone-kaniko/pkg/utils"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/urfave/cli"
 )
 
@@ -268,3 +271,71 @@ func TestGCRAuthSetup(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRegistryKeychain(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", "")
+	tests := []struct {
+		name  string
+		build kaniko.Build
+		want  bool
+	}{
+		{name: "build only", build: kaniko.Build{}},
+		{name: "verify push", build: kaniko.Build{VerifyPush: true}, want: true},
+		{name: "tag remotely", build: kaniko.Build{TagRemotely: true}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := withRegistryKeychain(kaniko.Plugin{Build: tt.build}, "gcr.io", "{}")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := plugin.Keychain != nil; got != tt.want {
+				t.Fatalf("Keychain set = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			registry, _ := name.NewRegistry("gcr.io")
+			auth, err := plugin.Keychain.Resolve(registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := auth.Authorization()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Username != "_json_key" || cfg.Password != "{}" {
+				t.Errorf("Resolve() = %s:%s, want the JSON key", cfg.Username, cfg.Password)
+			}
+		})
+	}
+}
+
+func TestSetupAuthDryRun(t *testing.T) {
+	t.Setenv(gcrEnvVariable, "")
+	const jsonKey = `{"type":"service_account","project_id":"test"}`
+	tests := []struct {
+		name              string
+		jsonKey           string
+		baseImageRegistry string
+		want              []string
+	}{
+		{name: "without json key", baseImageRegistry: "docker.io"},
+		{name: "without base image registry", jsonKey: jsonKey, want: []string{"gcr.io"}},
+		{name: "with base image registry", jsonKey: jsonKey, baseImageRegistry: "docker.io", want: []string{"gcr.io", "docker.io"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setupAuth(tt.jsonKey, "gcr.io", "user", "pass", tt.baseImageRegistry, true)
+			if err != nil {
+				t.Fatalf("setupAuth() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("setupAuth() = %v, want %v", got, tt.want)
+			}
+			if os.Getenv(gcrEnvVariable) != "" {
+				t.Errorf("setupAuth() set %s in a dry run", gcrEnvVariable)
+			}
+		})
+	}
+}
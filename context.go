@@ -0,0 +1,127 @@
+package kaniko
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Types of build contexts, see ContextType.
+const (
+	ContextDir       = "dir"    // local directory, the default
+	ContextGit       = "git"    // git://host/repo.git#ref:subdir
+	ContextS3        = "s3"     // s3://bucket/context.tar.gz
+	ContextGCS       = "gs"     // gs://bucket/context.tar.gz
+	ContextAzureBlob = "azblob" // https://account.blob.core.windows.net/container/context.tar.gz
+	ContextTar       = "tar"    // tar://path/to/context.tar.gz
+)
+
+// azureBlobHostRegexp matches the hosts of Azure Blob Storage accounts, in
+// the public and the sovereign clouds.
+var azureBlobHostRegexp = regexp.MustCompile(`^[a-z0-9]+\.blob\.core\.(windows\.net|chinacloudapi\.cn|cloudapi\.de|usgovcloudapi\.net)$`)
+
+// commitRegexp matches a full commit SHA.
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ContextType returns the type of the build context: ContextDir for paths
+// and dir:// URLs, the scheme for git, s3, gs and tar URLs, ContextAzureBlob
+// for https URLs and an empty string for unknown schemes.
+func ContextType(context string) string {
+	scheme, _, ok := strings.Cut(context, "://")
+	if !ok {
+		return ContextDir
+	}
+	switch scheme = strings.ToLower(scheme); scheme {
+	case ContextDir, ContextGit, ContextS3, ContextGCS, ContextTar:
+		return scheme
+	case "https":
+		return ContextAzureBlob
+	}
+	return ""
+}
+
+// withContext returns the build with the remote build context validated and
+// converted to the form the executor expects. The ref and subdirectory of a
+// git context, e.g. git://github.com/octocat/hello-world.git#main:docker,
+// are passed as refs/heads/main and the context sub-path docker.
+func (b Build) withContext() (Build, error) {
+	context := b.Context
+	switch ContextType(context) {
+	case ContextDir:
+		b.Context = strings.TrimPrefix(context, "dir://")
+		return b, nil
+	case ContextGit:
+		return b.withGitContext()
+	case ContextS3, ContextGCS:
+		u, err := url.Parse(context)
+		if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return b, fmt.Errorf("invalid build context %s, expected %s://bucket/path/to/context.tar.gz", context, ContextType(context))
+		}
+		return b, nil
+	case ContextAzureBlob:
+		u, err := url.Parse(context)
+		if err != nil || !azureBlobHostRegexp.MatchString(u.Host) {
+			return b, fmt.Errorf("invalid build context %s, https contexts must be Azure Blob Storage URLs such as https://account.blob.core.windows.net/container/context.tar.gz", context)
+		}
+		if container, blob, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/"); container == "" || blob == "" {
+			return b, fmt.Errorf("invalid build context %s, expected a container and a blob path", context)
+		}
+		return b, nil
+	case ContextTar:
+		file := context[len("tar://"):]
+		if file == "" {
+			return b, fmt.Errorf("invalid build context %s, expected tar://path/to/context.tar.gz", context)
+		}
+		if file != "stdin" {
+			if _, err := os.Stat(file); err != nil {
+				return b, fmt.Errorf("build context tarball does not exist at path: %s", file)
+			}
+		}
+		return b, nil
+	}
+	return b, fmt.Errorf("unsupported build context %s, expected a path or a dir, git, s3, gs, tar or Azure Blob Storage https URL", context)
+}
+
+// withGitContext returns the build with the ref and subdirectory fragment of
+// the git context converted. A ref is a branch name, a full ref such as
+// refs/tags/v1.0.0 or a commit SHA.
+func (b Build) withGitContext() (Build, error) {
+	original := b.Context
+	repo, fragment, _ := strings.Cut(original[len("git://"):], "#")
+	if u, err := url.Parse("https://" + repo); err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return b, fmt.Errorf("invalid build context %s, expected git://host/repository.git#ref:subdir", original)
+	}
+	ref, subdir, _ := strings.Cut(fragment, ":")
+	if strings.Contains(ref, "#") {
+		return b, fmt.Errorf("invalid git ref %s of build context %s", ref, original)
+	}
+
+	context := "git://" + repo
+	switch {
+	case ref == "":
+	case commitRegexp.MatchString(ref):
+		context += "##" + ref
+	case strings.HasPrefix(ref, "refs/"):
+		context += "#" + ref
+	default:
+		context += "#refs/heads/" + ref
+	}
+	b.Context = context
+
+	if subdir = strings.Trim(subdir, "/"); subdir != "" {
+		if cleaned := path.Clean(subdir); cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return b, fmt.Errorf("invalid subdirectory %s of build context %s", subdir, original)
+		}
+		b.ContextSubPath = path.Join(subdir, b.ContextSubPath)
+	}
+	return b, nil
+}
+
+// remoteContext returns true if the build context is not a local directory,
+// in which case the Dockerfile is read from the context by the executor.
+func (b Build) remoteContext() bool {
+	return ContextType(b.Context) != ContextDir
+}
@@ -0,0 +1,121 @@
+package kaniko
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithContext(t *testing.T) {
+	dir := t.TempDir()
+	tarball := filepath.Join(dir, "context.tar.gz")
+	if err := os.WriteFile(tarball, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	const sha = "8f51ad7884c5eb69c11d260a31da7a745e6b78e2"
+
+	tests := []struct {
+		name    string
+		build   Build
+		want    Build
+		wantErr bool
+	}{
+		{name: "path", build: Build{Context: "."}, want: Build{Context: "."}},
+		{name: "empty", build: Build{}, want: Build{}},
+		{name: "dir", build: Build{Context: "dir:///drone/src"}, want: Build{Context: "/drone/src"}},
+		{
+			name:  "git",
+			build: Build{Context: "git://github.com/octocat/hello-world.git"},
+			want:  Build{Context: "git://github.com/octocat/hello-world.git"},
+		},
+		{
+			name:  "git branch",
+			build: Build{Context: "git://github.com/octocat/hello-world.git#main"},
+			want:  Build{Context: "git://github.com/octocat/hello-world.git#refs/heads/main"},
+		},
+		{
+			name:  "git tag and subdirectory",
+			build: Build{Context: "git://github.com/octocat/hello-world.git#refs/tags/v1.0.0:docker/app/"},
+			want:  Build{Context: "git://github.com/octocat/hello-world.git#refs/tags/v1.0.0", ContextSubPath: "docker/app"},
+		},
+		{
+			name:  "git commit",
+			build: Build{Context: "git://github.com/octocat/hello-world.git#" + sha},
+			want:  Build{Context: "git://github.com/octocat/hello-world.git##" + sha},
+		},
+		{
+			name:  "git subdirectory and sub-path",
+			build: Build{Context: "git://github.com/octocat/hello-world.git#:services", ContextSubPath: "api"},
+			want:  Build{Context: "git://github.com/octocat/hello-world.git", ContextSubPath: "services/api"},
+		},
+		{name: "git without repository", build: Build{Context: "git://github.com"}, wantErr: true},
+		{name: "git subdirectory outside", build: Build{Context: "git://github.com/octocat/hello-world.git#main:../etc"}, wantErr: true},
+		{name: "s3", build: Build{Context: "s3://builds/context.tar.gz"}, want: Build{Context: "s3://builds/context.tar.gz"}},
+		{name: "s3 without key", build: Build{Context: "s3://builds"}, wantErr: true},
+		{name: "gs", build: Build{Context: "gs://builds/app/context.tar.gz"}, want: Build{Context: "gs://builds/app/context.tar.gz"}},
+		{
+			name:  "azure blob",
+			build: Build{Context: "https://contexts.blob.core.windows.net/builds/context.tar.gz"},
+			want:  Build{Context: "https://contexts.blob.core.windows.net/builds/context.tar.gz"},
+		},
+		{name: "azure blob without blob", build: Build{Context: "https://contexts.blob.core.windows.net/builds"}, wantErr: true},
+		{name: "https", build: Build{Context: "https://example.com/context.tar.gz"}, wantErr: true},
+		{name: "tar", build: Build{Context: "tar://" + tarball}, want: Build{Context: "tar://" + tarball}},
+		{name: "tar stdin", build: Build{Context: "tar://stdin"}, want: Build{Context: "tar://stdin"}},
+		{name: "tar missing", build: Build{Context: "tar://" + filepath.Join(dir, "missing.tar.gz")}, wantErr: true},
+		{name: "unknown scheme", build: Build{Context: "ftp://example.com/context.tar.gz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build.withContext()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expect error, got %s", got.Context)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(Build{})); diff != "" {
+				t.Errorf("withContext() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecRemoteContext(t *testing.T) {
+	var args []string
+	p := Plugin{
+		Build: Build{
+			Dockerfile: "Dockerfile",
+			Context:    "git://github.com/octocat/hello-world.git#main:app",
+			Git:        true,
+			NoPush:     true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			args = e.Args
+			return Result{}, nil
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--context") || strings.HasPrefix(arg, "--git") {
+			got = append(got, arg)
+		}
+	}
+	want := []string{
+		"--context=git://github.com/octocat/hello-world.git#refs/heads/main",
+		"--context-sub-path=app",
+		"--git=single-branch=true",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("executor args mismatch (-want +got):\n%s", diff)
+	}
+}
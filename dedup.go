@@ -0,0 +1,224 @@
+package kaniko
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/drone/drone-kaniko/pkg/artifact"
+	"github.com/drone/drone-kaniko/pkg/dockerignore"
+	"github.com/drone/drone-kaniko/pkg/output"
+)
+
+const (
+	// contentKeyLabel is the image label holding the content key of a build.
+	contentKeyLabel = "org.drone.kaniko.content-key"
+
+	// contentKeyTagPrefix prefixes the tag an image is pushed to under its
+	// content key, so that later builds can look it up.
+	contentKeyTagPrefix = "content-"
+)
+
+// contentKey returns a key identifying the inputs of the build: the
+// Dockerfile, the build args, the target and platforms, and the files of the
+// build context that are not excluded by .dockerignore.
+func (p Plugin) contentKey() (string, error) {
+	h := sha256.New()
+
+	dockerfile, err := os.ReadFile(p.Build.Dockerfile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dockerfile: %v", err)
+	}
+	fmt.Fprintf(h, "dockerfile %x\n", sha256.Sum256(dockerfile))
+
+	args := append(append([]string{}, p.Build.Args...), p.Build.ArgsNew...)
+	sort.Strings(args)
+	for _, arg := range args {
+		fmt.Fprintf(h, "arg %q\n", arg)
+	}
+
+	platforms := append([]string{}, p.Build.Platforms...)
+	sort.Strings(platforms)
+	fmt.Fprintf(h, "target %q\nplatform %q\nplatforms %q\n", p.Build.Target, p.Build.CustomPlatform, platforms)
+
+	context := p.Build.Context
+	if context == "" {
+		context = "."
+	}
+	contextDigest, err := hashContext(filepath.Join(context, p.Build.ContextSubPath))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "context %s\n", contextDigest)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashContext returns a digest of the paths, modes and contents of every file
+// in the build context that is not excluded by its .dockerignore file. The
+// .git directory is always skipped, as it changes with every commit even when
+// the files of the context do not.
+func hashContext(dir string) (string, error) {
+	ignore, err := dockerignore.ReadFile(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.Matches(rel) {
+			// children of an ignored directory can only be part of the
+			// context again through an exclusion pattern
+			if d.IsDir() && !ignore.HasExclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			fmt.Fprintf(h, "dir %q\n", rel)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "symlink %q %q\n", rel, target)
+		case info.Mode().IsRegular():
+			sum, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "file %q %o %x\n", rel, info.Mode().Perm(), sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash build context %s: %v", dir, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the sha256 digest of the file contents. The file is
+// closed before it returns, so a walk does not keep every file open.
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// findDuplicate looks up the image pushed under the content key in the
+// repository and returns its digest, or an empty digest when there is none.
+// An image found by tag must also carry the content key as a label.
+func (p Plugin) findDuplicate(key string) (string, error) {
+	ref, err := name.ParseReference(fmt.Sprintf("%s:%s", p.Build.Repo, contentKeyTag(key)), p.Build.nameOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("invalid content key reference: %v", err)
+	}
+	desc, err := remote.Get(ref, p.remoteOptions()...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to look up %s: %v", ref, err)
+	}
+
+	if desc.MediaType.IsImage() {
+		img, err := desc.Image()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", ref, err)
+		}
+		config, err := img.ConfigFile()
+		if err != nil {
+			return "", fmt.Errorf("failed to read config of %s: %v", ref, err)
+		}
+		if config.Config.Labels[contentKeyLabel] != key {
+			fmt.Printf("Ignoring %s, it does not carry the content key label\n", ref)
+			return "", nil
+		}
+	}
+	return desc.Digest.String(), nil
+}
+
+// reuseBuild tags the existing image with every label of the tags instead of
+// building it, and writes the digest, artifact and output files.
+func (p Plugin) reuseBuild(digest string, tags []string) error {
+	labels := p.Build.expandTags(tags)
+	fmt.Printf("Found an image with the same content key at %s@%s, skipping the build\n", p.Build.Repo, digest)
+
+	if err := p.checkImmutableTags(digest, labels, p.Build.nameOptions(), p.remoteOptions()); err != nil {
+		return err
+	}
+	if err := p.tagRemotely(digest, labels); err != nil {
+		return err
+	}
+	if p.Build.VerifyPush {
+		if err := p.verifyTags(digest, labels); err != nil {
+			return err
+		}
+	}
+
+	if p.Build.DigestFile != "" {
+		if err := os.WriteFile(p.Build.DigestFile, []byte(digest), 0644); err != nil {
+			return fmt.Errorf("failed to write digest file at path: %s: %v", p.Build.DigestFile, err)
+		}
+	}
+
+	if p.Artifact.ArtifactFile != "" {
+		err := artifact.WritePluginArtifactFile(p.Artifact.RegistryType, p.Artifact.ArtifactFile, p.Artifact.Registry, p.Artifact.Repo, digest, p.Artifact.Tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write plugin artifact file at path: %s with error: %s\n", p.Artifact.ArtifactFile, err)
+		}
+	}
+
+	outputFile := os.Getenv("DRONE_OUTPUT")
+	if err := output.WritePluginOutputFileTags(outputFile, digest, "", labels); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write plugin output file at path: %s with error: %s\n", outputFile, err)
+	}
+	return nil
+}
+
+// contentKeyTag returns the tag an image is pushed to under its content key.
+func contentKeyTag(key string) string {
+	return contentKeyTagPrefix + key
+}
@@ -0,0 +1,221 @@
+package kaniko
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// writeFiles creates the files, relative to dir, with the given contents.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore":  "docs\n*.md\n",
+		"main.go":        "package main\n",
+		"README.md":      "readme\n",
+		"docs/guide.txt": "guide\n",
+	})
+	want, err := hashContext(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes to ignored files keep the hash
+	writeFiles(t, dir, map[string]string{
+		"README.md":      "updated readme\n",
+		"docs/guide.txt": "updated guide\n",
+		"docs/new.txt":   "new\n",
+	})
+	if got, _ := hashContext(dir); got != want {
+		t.Errorf("hash changed after editing ignored files")
+	}
+
+	// changes to the context change the hash
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	if got, _ := hashContext(dir); got == want {
+		t.Errorf("hash did not change after editing main.go")
+	}
+}
+
+func TestHashContextSkipsGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":   "package main\n",
+		".git/HEAD": "ref: refs/heads/main\n",
+	})
+	want, err := hashContext(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFiles(t, dir, map[string]string{".git/HEAD": "3f786850e387550fdab836ed7e6dc881de23001b\n"})
+	if got, _ := hashContext(dir); got != want {
+		t.Errorf("hash changed after a change to .git/HEAD")
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Dockerfile": "FROM scratch\n", "main.go": "package main\n"})
+	build := Build{
+		Dockerfile: filepath.Join(dir, "Dockerfile"),
+		Context:    dir,
+		Args:       []string{"A=1", "B=2"},
+		Target:     "release",
+	}
+	want, err := Plugin{Build: build}.contentKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reordered := build
+	reordered.Args = []string{"B=2", "A=1"}
+	if got, _ := (Plugin{Build: reordered}).contentKey(); got != want {
+		t.Errorf("content key depends on the order of build args")
+	}
+
+	for name, changed := range map[string]Build{
+		"args":     {Dockerfile: build.Dockerfile, Context: dir, Args: []string{"A=1", "B=3"}, Target: "release"},
+		"target":   {Dockerfile: build.Dockerfile, Context: dir, Args: build.Args, Target: "debug"},
+		"platform": {Dockerfile: build.Dockerfile, Context: dir, Args: build.Args, Target: "release", CustomPlatform: "linux/arm64"},
+	} {
+		if got, _ := (Plugin{Build: changed}).contentKey(); got == want {
+			t.Errorf("content key did not change with %s", name)
+		}
+	}
+}
+
+func TestExecDedup(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Dockerfile": "FROM scratch\n", "main.go": "package main\n"})
+
+	runs := 0
+	executor := fakeExecutor(t)
+	p := Plugin{
+		Build: Build{
+			Dockerfile: filepath.Join(dir, "Dockerfile"),
+			Context:    dir,
+			Repo:       host + "/foo/bar",
+			Tags:       []string{"first"},
+			DigestFile: filepath.Join(t.TempDir(), "digest"),
+			Dedup:      true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			runs++
+			return executor.Run(e)
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := getDigest(p.Build.DigestFile)
+
+	p.Build.Tags = []string{"second"}
+	if err := os.Remove(p.Build.DigestFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("executor runs = %d, want 1", runs)
+	}
+	if got := getDigest(p.Build.DigestFile); got != want {
+		t.Errorf("digest file = %s, want %s", got, want)
+	}
+	ref, _ := name.ParseReference(host + "/foo/bar:second")
+	desc, err := remote.Head(ref)
+	if err != nil {
+		t.Fatalf("missing tag second: %v", err)
+	}
+	if desc.Digest.String() != want {
+		t.Errorf("digest of tag second = %s, want %s", desc.Digest, want)
+	}
+
+	// a change to the context builds again
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("executor runs = %d, want 2", runs)
+	}
+}
+
+func TestExecDedupContentTag(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Dockerfile": "FROM scratch\n"})
+	outputFile := filepath.Join(t.TempDir(), "output.env")
+	t.Setenv("DRONE_OUTPUT", outputFile)
+
+	var destinations []string
+	executor := fakeExecutor(t)
+	p := Plugin{
+		Build: Build{
+			Dockerfile: filepath.Join(dir, "Dockerfile"),
+			Context:    dir,
+			Repo:       host + "/foo/bar",
+			Tags:       []string{"latest"},
+			Dedup:      true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			for _, arg := range e.Args {
+				if strings.HasPrefix(arg, "--destination=") {
+					destinations = append(destinations, strings.TrimPrefix(arg, "--destination="))
+				}
+			}
+			return executor.Run(e)
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the content key tag is pushed, but not built or reported as a tag
+	if want := []string{host + "/foo/bar:latest"}; !cmp.Equal(want, destinations) {
+		t.Errorf("destinations mismatch (-want +got):\n%s", cmp.Diff(want, destinations))
+	}
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), contentKeyTagPrefix) {
+		t.Errorf("output file reports the content key tag:\n%s", output)
+	}
+	key, err := p.contentKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, _ := name.ParseReference(host + "/foo/bar:latest")
+	content, _ := name.ParseReference(host + "/foo/bar:" + contentKeyTag(key))
+	want, err := remote.Head(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := remote.Head(content)
+	if err != nil {
+		t.Fatalf("missing content key tag: %v", err)
+	}
+	if got.Digest != want.Digest {
+		t.Errorf("digest of the content key tag = %s, want %s", got.Digest, want.Digest)
+	}
+}
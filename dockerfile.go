@@ -0,0 +1,92 @@
+package kaniko
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dockerfileClient fetches remote Dockerfiles.
+var dockerfileClient = &http.Client{Timeout: time.Minute}
+
+// maxDockerfileSize is the size above which a remote Dockerfile is rejected.
+const maxDockerfileSize = 10 << 20
+
+// withDockerfile returns the build with the inline or remote Dockerfile
+// written to a temporary file, and a function removing it. The build is
+// returned unchanged when neither is set.
+func (b Build) withDockerfile() (Build, func(), error) {
+	cleanup := func() {}
+	if b.DockerfileContent == "" && b.DockerfileURL == "" {
+		return b, cleanup, nil
+	}
+	if b.DockerfileContent != "" && b.DockerfileURL != "" {
+		return b, cleanup, fmt.Errorf("inputs dockerfile-content and dockerfile-url cannot be used together. please define only one")
+	}
+
+	content := []byte(b.DockerfileContent)
+	if b.DockerfileURL != "" {
+		var err error
+		if content, err = b.fetchDockerfile(); err != nil {
+			return b, cleanup, err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "drone-kaniko-dockerfile")
+	if err != nil {
+		return b, cleanup, fmt.Errorf("failed to create directory for dockerfile: %v", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	path := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		cleanup()
+		return b, func() {}, fmt.Errorf("failed to write dockerfile: %v", err)
+	}
+	b.Dockerfile = path
+	return b, cleanup, nil
+}
+
+// fetchDockerfile downloads the remote Dockerfile, authenticating with the
+// token or the username and password, and verifies its checksum if set.
+func (b Build) fetchDockerfile() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, b.DockerfileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dockerfile url: %v", err)
+	}
+	if b.DockerfileToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.DockerfileToken)
+	} else if b.DockerfileUsername != "" {
+		req.SetBasicAuth(b.DockerfileUsername, b.DockerfilePassword)
+	}
+
+	res, err := dockerfileClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dockerfile: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch dockerfile from %s: %s", req.URL.Redacted(), res.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(res.Body, maxDockerfileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dockerfile: %v", err)
+	}
+	if len(content) > maxDockerfileSize {
+		return nil, fmt.Errorf("dockerfile at %s is larger than %d bytes", req.URL.Redacted(), maxDockerfileSize)
+	}
+
+	if b.DockerfileChecksum != "" {
+		want := strings.ToLower(strings.TrimPrefix(b.DockerfileChecksum, "sha256:"))
+		sum := sha256.Sum256(content)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return nil, fmt.Errorf("checksum mismatch of dockerfile at %s: got sha256:%s, want sha256:%s", req.URL.Redacted(), got, want)
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,151 @@
+package kaniko
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithDockerfile(t *testing.T) {
+	const dockerfile = "FROM alpine:3.20\nRUN apk add --no-cache git\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/bearer" && r.Header.Get("Authorization") != "Bearer s3cr3t-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/basic":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "octocat" || pass != "passw0rd" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(dockerfile))
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte(dockerfile))
+		}
+	}))
+	defer server.Close()
+	const checksum = "sha256:24a4d9c316d43d91deb578356fded68d4a9a019b77ba9735d7cf4406a44175b1"
+
+	tests := []struct {
+		name    string
+		build   Build
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "local",
+			build: Build{Dockerfile: "Dockerfile"},
+		},
+		{
+			name:  "content",
+			build: Build{Dockerfile: "Dockerfile", DockerfileContent: dockerfile},
+			want:  dockerfile,
+		},
+		{
+			name:  "url",
+			build: Build{DockerfileURL: server.URL + "/Dockerfile"},
+			want:  dockerfile,
+		},
+		{
+			name:  "bearer token",
+			build: Build{DockerfileURL: server.URL + "/bearer", DockerfileToken: "s3cr3t-token"},
+			want:  dockerfile,
+		},
+		{
+			name:    "missing bearer token",
+			build:   Build{DockerfileURL: server.URL + "/bearer"},
+			wantErr: "401 Unauthorized",
+		},
+		{
+			name:  "basic auth",
+			build: Build{DockerfileURL: server.URL + "/basic", DockerfileUsername: "octocat", DockerfilePassword: "passw0rd"},
+			want:  dockerfile,
+		},
+		{
+			name:    "not found",
+			build:   Build{DockerfileURL: server.URL + "/missing"},
+			wantErr: "404 Not Found",
+		},
+		{
+			name:  "checksum",
+			build: Build{DockerfileURL: server.URL + "/Dockerfile", DockerfileChecksum: checksum},
+			want:  dockerfile,
+		},
+		{
+			name:    "checksum mismatch",
+			build:   Build{DockerfileURL: server.URL + "/Dockerfile", DockerfileChecksum: "sha256:0000"},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "content and url",
+			build:   Build{DockerfileContent: dockerfile, DockerfileURL: server.URL + "/Dockerfile"},
+			wantErr: "cannot be used together",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cleanup, err := tt.build.withDockerfile()
+			defer cleanup()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expect error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if got.Dockerfile != tt.build.Dockerfile {
+					t.Errorf("Dockerfile = %s, want %s", got.Dockerfile, tt.build.Dockerfile)
+				}
+				return
+			}
+			content, err := os.ReadFile(got.Dockerfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("Dockerfile content = %q, want %q", content, tt.want)
+			}
+			cleanup()
+			if _, err := os.Stat(got.Dockerfile); !os.IsNotExist(err) {
+				t.Errorf("Dockerfile %s not removed: %v", got.Dockerfile, err)
+			}
+		})
+	}
+}
+
+func TestExecDockerfileContent(t *testing.T) {
+	var dockerfile, content string
+	p := Plugin{
+		Build: Build{
+			Dockerfile:        "missing/Dockerfile",
+			DockerfileContent: "FROM scratch\n",
+			Context:           t.TempDir(),
+			NoPush:            true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			for _, arg := range e.Args {
+				if strings.HasPrefix(arg, "--dockerfile=") {
+					dockerfile = strings.TrimPrefix(arg, "--dockerfile=")
+				}
+			}
+			data, err := os.ReadFile(dockerfile)
+			content = string(data)
+			return Result{}, err
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if content != "FROM scratch\n" {
+		t.Errorf("Dockerfile content = %q", content)
+	}
+	if _, err := os.Stat(dockerfile); !os.IsNotExist(err) {
+		t.Errorf("Dockerfile %s not removed: %v", dockerfile, err)
+	}
+}
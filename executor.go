@@ -0,0 +1,62 @@
+package kaniko
+
+import (
+	"io"
+	"os/exec"
+	"time"
+)
+
+// defaultExecutorPath is the location of the kaniko executor in the plugin images.
+const defaultExecutorPath = "/kaniko/executor"
+
+type (
+	// Execution defines a single invocation of the kaniko executor.
+	Execution struct {
+		Path   string    // Executor binary path
+		Args   []string  // Executor arguments computed from the build configuration
+		Env    []string  // Executor environment in "key=value" form
+		Stdin  io.Reader // Executor standard input
+		Stdout io.Writer // Executor standard output
+		Stderr io.Writer // Executor standard error
+	}
+
+	// Result defines the outcome of an executor invocation.
+	Result struct {
+		ExitCode int           // Executor exit code, -1 if the process did not exit
+		Duration time.Duration // Wall clock time spent in the executor
+	}
+
+	// Runner runs the kaniko executor. Implementations may replace the
+	// executor entirely, e.g. in tests, or wrap another Runner to add
+	// behaviour such as timing or log capture.
+	Runner interface {
+		Run(Execution) (Result, error)
+	}
+
+	// RunnerFunc adapts an ordinary function to the Runner interface.
+	RunnerFunc func(Execution) (Result, error)
+)
+
+// Run calls f(e).
+func (f RunnerFunc) Run(e Execution) (Result, error) {
+	return f(e)
+}
+
+// execRunner runs the executor as a child process.
+type execRunner struct{}
+
+func (execRunner) Run(e Execution) (Result, error) {
+	cmd := exec.Command(e.Path, e.Args...)
+	cmd.Env = e.Env
+	cmd.Stdin = e.Stdin
+	cmd.Stdout = e.Stdout
+	cmd.Stderr = e.Stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := Result{ExitCode: -1, Duration: time.Since(start)}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return result, err
+}
@@ -0,0 +1,99 @@
+package kaniko
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExecRunner(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		build    Build
+		runErr   error
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name: "no_push",
+			build: Build{
+				Dockerfile: dockerfile,
+				Context:    dir,
+				Tags:       []string{"latest"},
+				NoPush:     true,
+			},
+			wantArgs: []string{
+				"--dockerfile=" + dockerfile,
+				"--context=dir://" + dir,
+				"--no-push",
+			},
+		},
+		{
+			name: "push_with_expanded_tags",
+			build: Build{
+				Dockerfile: dockerfile,
+				Context:    dir,
+				Repo:       "foo/bar",
+				Tags:       []string{"v1.2.3"},
+				ExpandTag:  true,
+				Args:       []string{"A=b"},
+			},
+			wantArgs: []string{
+				"--dockerfile=" + dockerfile,
+				"--context=dir://" + dir,
+				"--destination=foo/bar:1",
+				"--destination=foo/bar:1.2",
+				"--destination=foo/bar:1.2.3",
+				"--build-arg", "A=b",
+			},
+		},
+		{
+			name: "executor_fails",
+			build: Build{
+				Dockerfile: dockerfile,
+				Context:    dir,
+				NoPush:     true,
+			},
+			runErr:  fmt.Errorf("exit status 1"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Execution
+			p := Plugin{
+				Build: tt.build,
+				Runner: RunnerFunc(func(e Execution) (Result, error) {
+					got = e
+					return Result{}, tt.runErr
+				}),
+			}
+
+			err := p.Exec()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Path != defaultExecutorPath {
+				t.Errorf("executor path = %q, want %q", got.Path, defaultExecutorPath)
+			}
+			if !cmp.Equal(got.Args, tt.wantArgs) {
+				t.Errorf("executor args = %q, want %q", got.Args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package kaniko
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// withExistingTags returns the build with the tags that already exist in the
+// repository, which decide whether floating tags may be moved. Tags are only
+// listed once, and only when floating tags apply to the build. In dry-run
+// mode no registry is called and floating tags are resolved as if the
+// repository was empty.
+func (b Build) withExistingTags(nameOpts []name.Option, remoteOpts []remote.Option) (Build, error) {
+	if !b.FloatingTags || !(b.ExpandTag || b.AutoTag) || b.NoPush || b.DryRun || b.existingTags != nil {
+		return b, nil
+	}
+	repo, err := name.NewRepository(b.Repo, nameOpts...)
+	if err != nil {
+		return b, fmt.Errorf("invalid repository %s: %v", b.Repo, err)
+	}
+	tags, err := remote.List(repo, remoteOpts...)
+	if err != nil {
+		var terr *transport.Error
+		if !errors.As(err, &terr) || terr.StatusCode != http.StatusNotFound {
+			return b, fmt.Errorf("failed to list the tags of %s: %v", repo, err)
+		}
+	}
+	b.existingTags = append([]string{}, tags...)
+	return b, nil
+}
+
+// moveLatest returns true if the latest tag may be moved to the build, that
+// is when no tag of the build is a release version lower than a release in
+// the repository.
+func (b Build) moveLatest(tags []string) bool {
+	policy := b.tagPolicy()
+	for _, tag := range tags {
+		if !policy.MoveLatest(tag) {
+			return false
+		}
+	}
+	return true
+}
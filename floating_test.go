@@ -0,0 +1,198 @@
+package kaniko
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// pushTags pushes a random image to every tag of the repository.
+func pushTags(t *testing.T, repo string, tags ...string) {
+	t.Helper()
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range tags {
+		ref, err := name.ParseReference(repo + ":" + tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveTagsFloating(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []string
+		tags      []string
+		autoTag   bool
+		commitRef string
+		scheme    string
+		want      []string
+	}{
+		{
+			name: "first release",
+			tags: []string{"latest", "v1.4.9"},
+			want: []string{"latest", "1", "1.4", "1.4.9"},
+		},
+		{
+			name:     "highest release",
+			existing: []string{"latest", "1", "1.4", "1.4.8"},
+			tags:     []string{"latest", "v1.4.9"},
+			want:     []string{"latest", "1", "1.4", "1.4.9"},
+		},
+		{
+			name:     "hotfix of older major",
+			existing: []string{"latest", "2", "2.0", "2.0.0", "1.4.8"},
+			tags:     []string{"latest", "v1.4.9"},
+			want:     []string{"1", "1.4", "1.4.9"},
+		},
+		{
+			name:     "hotfix of older minor",
+			existing: []string{"1.5.0"},
+			tags:     []string{"latest", "v1.4.9"},
+			want:     []string{"1.4", "1.4.9"},
+		},
+		{
+			name:     "prerelease",
+			existing: []string{"2.0.0"},
+			tags:     []string{"latest", "v2.1.0-rc.1"},
+			want:     []string{"latest", "2.1.0-rc.1"},
+		},
+		{
+			name:      "auto tag",
+			existing:  []string{"1.5.0"},
+			autoTag:   true,
+			commitRef: "refs/tags/v1.4.9",
+			want:      []string{"1.4", "1.4.9"},
+		},
+		{
+			name:     "calver",
+			existing: []string{"2026.10.0"},
+			tags:     []string{"latest", "2026.09.4"},
+			scheme:   "calver",
+			want:     []string{"2026.09", "2026.09.4"},
+		},
+		{
+			name:      "calver auto tag",
+			existing:  []string{"2026.10.2"},
+			autoTag:   true,
+			commitRef: "refs/tags/2026.10.3",
+			scheme:    "calver",
+			want:      []string{"2026", "2026.10", "2026.10.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRegistry(t) + "/foo/bar"
+			if len(tt.existing) > 0 {
+				pushTags(t, repo, tt.existing...)
+			}
+			b := Build{
+				Repo:           repo,
+				Tags:           tt.tags,
+				ExpandTag:      !tt.autoTag,
+				AutoTag:        tt.autoTag,
+				DroneCommitRef: tt.commitRef,
+				AutoTagScheme:  tt.scheme,
+				FloatingTags:   true,
+			}
+			b, err := b.withExistingTags(nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got, err := b.ResolveTags()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ResolveTags() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecFloatingTags(t *testing.T) {
+	repo := newTestRegistry(t) + "/foo/bar"
+	pushTags(t, repo, "2.0.0")
+
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var destinations []string
+	p := Plugin{
+		Build: Build{
+			Dockerfile:   dockerfile,
+			Context:      dir,
+			Repo:         repo,
+			Tags:         []string{"latest", "v1.4.9"},
+			ExpandTag:    true,
+			FloatingTags: true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			for _, arg := range e.Args {
+				if strings.HasPrefix(arg, "--destination=") {
+					destinations = append(destinations, strings.TrimPrefix(arg, "--destination="))
+				}
+			}
+			return Result{}, nil
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{repo + ":1", repo + ":1.4", repo + ":1.4.9"}
+	if diff := cmp.Diff(want, destinations); diff != "" {
+		t.Errorf("destinations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// failingKeychain fails the test when the plugin resolves credentials, which
+// it does before any registry call.
+type failingKeychain struct{ t *testing.T }
+
+func (k failingKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	k.t.Errorf("unexpected registry call to %s", target.RegistryStr())
+	return nil, fmt.Errorf("unexpected registry call to %s", target.RegistryStr())
+}
+
+func TestExecDryRunFloatingTags(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{
+		Build: Build{
+			Dockerfile:   dockerfile,
+			Context:      dir,
+			Repo:         "registry.example.com/foo/bar",
+			Tags:         []string{"latest", "v1.4.9"},
+			ExpandTag:    true,
+			FloatingTags: true,
+			DryRun:       true,
+		},
+		Runner: RunnerFunc(func(Execution) (Result, error) {
+			t.Fatal("executor must not run")
+			return Result{}, nil
+		}),
+		Keychain: failingKeychain{t},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
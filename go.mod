@@ -9,7 +9,6 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/ecr v1.17.8
 	github.com/aws/aws-sdk-go-v2/service/ecrpublic v1.13.8
 	github.com/aws/smithy-go v1.12.0
-	github.com/coreos/go-semver v0.3.0
 	github.com/google/go-cmp v0.6.0
 	github.com/google/go-containerregistry v0.20.3
 	github.com/hashicorp/go-version v1.6.0
@@ -18,7 +17,6 @@ require (
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.11.1
 	github.com/urfave/cli v1.22.16
-	golang.org/x/mod v0.35.0
 )
 
 require (
@@ -0,0 +1,100 @@
+package kaniko
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// isImmutable returns true if the label is protected by the immutable tags
+// policy. The policy is a list of glob patterns, "all" or "true" protect
+// every tag.
+func (b Build) isImmutable(label string) bool {
+	for _, pattern := range b.ImmutableTags {
+		pattern = strings.TrimSpace(pattern)
+		switch strings.ToLower(pattern) {
+		case "":
+			continue
+		case "all", "true":
+			return true
+		}
+		if ok, _ := path.Match(pattern, label); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImmutableTags fails if a protected label already exists in the
+// repository with a digest other than the given one.
+func (p Plugin) checkImmutableTags(digest string, labels []string, nameOpts []name.Option, remoteOpts []remote.Option) error {
+	existing, err := p.existingImmutableTags(labels, nameOpts, remoteOpts)
+	if err != nil {
+		return err
+	}
+	return p.immutableConflicts(existing, digest)
+}
+
+// existingImmutableTags returns the digests of the protected labels that
+// already exist in the repository.
+func (p Plugin) existingImmutableTags(labels []string, nameOpts []name.Option, remoteOpts []remote.Option) (map[string]string, error) {
+	existing := make(map[string]string)
+	for _, label := range labels {
+		if !p.Build.isImmutable(label) {
+			continue
+		}
+		ref, err := name.ParseReference(fmt.Sprintf("%s:%s", p.Build.Repo, label), nameOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination %s:%s: %v", p.Build.Repo, label, err)
+		}
+		desc, err := remote.Head(ref, remoteOpts...)
+		if err != nil {
+			var terr *transport.Error
+			if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("failed to check immutable tag %s: %v", ref, err)
+		}
+		existing[label] = desc.Digest.String()
+	}
+	return existing, nil
+}
+
+// immutableConflicts fails if one of the existing protected labels points at
+// a digest other than the given one.
+func (p Plugin) immutableConflicts(existing map[string]string, digest string) error {
+	var labels []string
+	for label := range existing {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var conflicts []string
+	for _, label := range labels {
+		if existing[label] != digest {
+			conflicts = append(conflicts, fmt.Sprintf("%s:%s (%s)", p.Build.Repo, label, existing[label]))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("refusing to overwrite immutable tags with %s: %s", digest, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// withoutLabels returns the labels that are not in the set.
+func withoutLabels(labels []string, set map[string]string) []string {
+	var out []string
+	for _, label := range labels {
+		if _, ok := set[label]; !ok {
+			out = append(out, label)
+		}
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package kaniko
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestIsImmutable(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		label    string
+		want     bool
+	}{
+		{name: "no_policy", label: "v1.4.2", want: false},
+		{name: "all", patterns: []string{"all"}, label: "latest", want: true},
+		{name: "true", patterns: []string{"true"}, label: "latest", want: true},
+		{name: "glob_match", patterns: []string{"v*"}, label: "v1.4.2", want: true},
+		{name: "glob_no_match", patterns: []string{"v*"}, label: "latest", want: false},
+		{name: "several_patterns", patterns: []string{"v*", " 1.* "}, label: "1.4", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Build{ImmutableTags: tt.patterns}
+			if got := b.isImmutable(tt.label); got != tt.want {
+				t.Errorf("isImmutable(%q) = %v, want %v", tt.label, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecImmutableTags(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference(host + "/foo/bar:v1.4.2")
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{
+		Build: Build{
+			Dockerfile:    dockerfile,
+			Context:       dir,
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"latest", "v1.4.2"},
+			ImmutableTags: []string{"v*"},
+		},
+		Runner: fakeExecutor(t),
+	}
+	if err := p.Exec(); err == nil || !strings.Contains(err.Error(), "foo/bar:v1.4.2") {
+		t.Errorf("Expected an immutable tag error, got %v", err)
+	}
+	desc, err := remote.Head(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := img.Digest(); desc.Digest != want {
+		t.Errorf("immutable tag was overwritten with %s", desc.Digest)
+	}
+
+	// rebuilding the same image is allowed
+	var args []string
+	p.Runner = imageExecutor(t, img, &args)
+	if err := p.Exec(); err != nil {
+		t.Errorf("Unexpected error rebuilding the same digest: %v", err)
+	}
+	for _, arg := range args {
+		if arg == "--destination="+ref.String() {
+			t.Errorf("existing immutable tag must not be pushed by the executor")
+		}
+	}
+	p.Build.Tags = []string{"v1.4.2"}
+	if err := p.Exec(); err != nil {
+		t.Errorf("Unexpected error rebuilding the same digest: %v", err)
+	}
+	if !slices.Contains(args, "--no-push") {
+		t.Errorf("executor must not push when every tag exists, got %v", args)
+	}
+	p.Build.Tags = []string{"latest", "v1.4.2"}
+
+	// pushing the same digest again is allowed, another digest is not
+	same := filepath.Join(dir, "same.tar")
+	if err := tarball.WriteToFile(same, ref, img); err != nil {
+		t.Fatal(err)
+	}
+	other, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPath := filepath.Join(dir, "other.tar")
+	if err := tarball.WriteToFile(otherPath, ref, other); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Build.PushOnly = true
+	p.Build.SourceTarPath = same
+	if err := p.Exec(); err != nil {
+		t.Errorf("Unexpected error pushing the same digest: %v", err)
+	}
+	p.Build.SourceTarPath = otherPath
+	if err := p.Exec(); err == nil {
+		t.Errorf("Expected an immutable tag error, but got none")
+	}
+}
+
+// imageExecutor returns a Runner that pushes the image to every --destination
+// and records its digest in --digest-file, saving the arguments to args.
+func imageExecutor(t *testing.T, img v1.Image, args *[]string) Runner {
+	return RunnerFunc(func(e Execution) (Result, error) {
+		*args = e.Args
+		for _, arg := range e.Args {
+			switch {
+			case strings.HasPrefix(arg, "--destination="):
+				ref, err := name.ParseReference(strings.TrimPrefix(arg, "--destination="))
+				if err != nil {
+					return Result{ExitCode: 1}, err
+				}
+				if err := remote.Write(ref, img); err != nil {
+					return Result{ExitCode: 1}, err
+				}
+			case strings.HasPrefix(arg, "--digest-file="):
+				digest, _ := img.Digest()
+				if err := os.WriteFile(strings.TrimPrefix(arg, "--digest-file="), []byte(digest.String()), 0644); err != nil {
+					return Result{ExitCode: 1}, err
+				}
+			}
+		}
+		return Result{}, nil
+	})
+}
+
+func TestExecPlatformsImmutableTags(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference(host + "/foo/bar:v1-linux-amd64")
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Plugin{
+		Build: Build{
+			Dockerfile:    dockerfile,
+			Context:       dir,
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"v1"},
+			Platforms:     []string{"linux/amd64"},
+			ImmutableTags: []string{"v*"},
+		},
+		Runner: fakeExecutor(t),
+	}
+	if err := p.Exec(); err == nil || !strings.Contains(err.Error(), "foo/bar:v1-linux-amd64") {
+		t.Errorf("Expected an immutable tag error, got %v", err)
+	}
+
+	var args []string
+	p.Runner = imageExecutor(t, img, &args)
+	if err := p.Exec(); err != nil {
+		t.Errorf("Unexpected error rebuilding the same digest: %v", err)
+	}
+	if !slices.Contains(args, "--no-push") {
+		t.Errorf("existing immutable platform tag must not be pushed, got %v", args)
+	}
+}
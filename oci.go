@@ -0,0 +1,110 @@
+package kaniko
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// OCI image annotation keys, also set as image labels.
+const (
+	ociCreated  = "org.opencontainers.image.created"
+	ociURL      = "org.opencontainers.image.url"
+	ociSource   = "org.opencontainers.image.source"
+	ociVersion  = "org.opencontainers.image.version"
+	ociRevision = "org.opencontainers.image.revision"
+)
+
+// ociKeys are the OCI keys populated by OCILabels.
+var ociKeys = []string{ociCreated, ociURL, ociSource, ociVersion, ociRevision, refNameAnnotation}
+
+// withOCILabels returns the labels of the build with the OCI labels populated
+// from the Drone metadata and the resolved tags appended. Labels without a
+// value are left out, and user provided labels win over them.
+func (b Build) withOCILabels(data TemplateData, tags []string) []string {
+	values := map[string]string{
+		ociCreated:  data.Timestamp.UTC().Format(time.RFC3339),
+		ociURL:      data.RepoLink,
+		ociSource:   data.Remote,
+		ociRevision: data.SHA,
+	}
+	if version := b.versionTag(tags); version != "" {
+		values[ociVersion] = version
+		values[refNameAnnotation] = version
+	} else if len(tags) > 0 {
+		values[refNameAnnotation] = tags[0]
+	}
+
+	user := make(map[string]bool)
+	for _, label := range b.Labels {
+		key, _, _ := strings.Cut(label, "=")
+		user[key] = true
+	}
+
+	labels := append([]string{}, b.Labels...)
+	var added []string
+	for key, value := range values {
+		if value != "" && !user[key] {
+			added = append(added, key+"="+value)
+		}
+	}
+	sort.Strings(added)
+	return append(labels, added...)
+}
+
+// versionTag returns the most specific tag that is a version, e.g. 1.2.3 of
+// the expanded tags 1, 1.2 and 1.2.3, or 2026.10.3 of 2026, 2026.10 and
+// 2026.10.3 under calver, which accepts two-part versions too. Of versions
+// with as many components the first is returned.
+func (b Build) versionTag(tags []string) string {
+	policy := b.tagPolicy()
+	version, components := "", 0
+	for _, tag := range tags {
+		if _, err := policy.Parse(tag); err != nil {
+			continue
+		}
+		if n := versionComponents(tag); n > components {
+			version, components = tag, n
+		}
+	}
+	return version
+}
+
+// versionComponents returns the number of dot separated components of the
+// version, not counting those of the pre-release and build metadata.
+func versionComponents(version string) int {
+	core, _, _ := strings.Cut(version, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return strings.Count(core, ".") + 1
+}
+
+// ociAnnotations returns the OCI keys among the labels as annotations.
+func ociAnnotations(labels []string) map[string]string {
+	annotations := make(map[string]string)
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		for _, oci := range ociKeys {
+			if key == oci {
+				annotations[key] = value
+			}
+		}
+	}
+	return annotations
+}
+
+// withAnnotations returns the source with the annotations set on its
+// manifest. This changes the digest of the source.
+func (s pushSource) withAnnotations(annotations map[string]string) pushSource {
+	if len(annotations) == 0 {
+		return s
+	}
+	if s.index != nil {
+		s.index = mutate.Annotations(s.index, annotations).(v1.ImageIndex)
+	} else {
+		s.image = mutate.Annotations(s.image, annotations).(v1.Image)
+	}
+	return s
+}
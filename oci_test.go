@@ -0,0 +1,185 @@
+package kaniko
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestWithOCILabels(t *testing.T) {
+	data := TemplateData{
+		SHA:       "8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+		RepoLink:  "https://github.com/octocat/hello-world",
+		Remote:    "https://github.com/octocat/hello-world.git",
+		Timestamp: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name   string
+		labels []string
+		tags   []string
+		scheme string
+		data   TemplateData
+		want   []string
+	}{
+		{
+			name: "version",
+			tags: []string{"1", "1.2", "1.2.3"},
+			data: data,
+			want: []string{
+				"org.opencontainers.image.created=2026-10-17T12:00:00Z",
+				"org.opencontainers.image.ref.name=1.2.3",
+				"org.opencontainers.image.revision=8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+				"org.opencontainers.image.source=https://github.com/octocat/hello-world.git",
+				"org.opencontainers.image.url=https://github.com/octocat/hello-world",
+				"org.opencontainers.image.version=1.2.3",
+			},
+		},
+		{
+			name:   "calver",
+			tags:   []string{"2026", "2026.10", "2026.10.3"},
+			scheme: "calver",
+			data:   TemplateData{Timestamp: data.Timestamp},
+			want: []string{
+				"org.opencontainers.image.created=2026-10-17T12:00:00Z",
+				"org.opencontainers.image.ref.name=2026.10.3",
+				"org.opencontainers.image.version=2026.10.3",
+			},
+		},
+		{
+			name:   "user labels win",
+			labels: []string{"org.opencontainers.image.source=https://example.com/src", "team=payments"},
+			tags:   []string{"latest"},
+			data:   data,
+			want: []string{
+				"org.opencontainers.image.source=https://example.com/src",
+				"team=payments",
+				"org.opencontainers.image.created=2026-10-17T12:00:00Z",
+				"org.opencontainers.image.ref.name=latest",
+				"org.opencontainers.image.revision=8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+				"org.opencontainers.image.url=https://github.com/octocat/hello-world",
+			},
+		},
+		{
+			name: "missing metadata",
+			data: TemplateData{Timestamp: data.Timestamp},
+			want: []string{
+				"org.opencontainers.image.created=2026-10-17T12:00:00Z",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Build{Labels: tt.labels, AutoTagScheme: tt.scheme}
+			got := b.withOCILabels(tt.data, tt.tags)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withOCILabels() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPushOCIAnnotations(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	t.Setenv("DRONE_COMMIT_SHA", "8f51ad7884c5eb69c11d260a31da7a745e6b78e2")
+	t.Setenv("DRONE_GIT_HTTP_URL", "https://github.com/octocat/hello-world.git")
+	t.Setenv("DRONE_BUILD_CREATED", "1792238400")
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference("foo/bar:latest")
+	sourceTarPath := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(sourceTarPath, ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Plugin{
+		Build: Build{
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"v1.2.3"},
+			ExpandTag:     true,
+			OCILabels:     true,
+			Labels:        []string{"org.opencontainers.image.revision=override"},
+			PushOnly:      true,
+			SourceTarPath: sourceTarPath,
+		},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pushed, _ := name.ParseReference(host + "/foo/bar:1.2.3")
+	got, err := remote.Image(pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := got.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"org.opencontainers.image.created":  "2026-10-17T12:00:00Z",
+		"org.opencontainers.image.ref.name": "1.2.3",
+		"org.opencontainers.image.revision": "override",
+		"org.opencontainers.image.source":   "https://github.com/octocat/hello-world.git",
+		"org.opencontainers.image.version":  "1.2.3",
+	}
+	if diff := cmp.Diff(want, manifest.Annotations); diff != "" {
+		t.Errorf("annotations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExecPushOnlyRendersTemplatesOnce(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	t.Setenv("DRONE_COMMIT_SHA", "8f51ad7884c5eb69c11d260a31da7a745e6b78e2")
+	t.Setenv("DRONE_COMMIT_BRANCH", "{{ .SHA }}")
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference("foo/bar:latest")
+	sourceTarPath := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(sourceTarPath, ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// the branch renders to a literal template, which must not be rendered
+	// again when the image is pushed
+	p := Plugin{
+		Build: Build{
+			Repo:            host + "/foo/bar",
+			Tags:            []string{"latest"},
+			OCILabels:       true,
+			RenderTemplates: true,
+			Labels:          []string{"org.opencontainers.image.url={{ .Branch }}"},
+			PushOnly:        true,
+			SourceTarPath:   sourceTarPath,
+		},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pushed, _ := name.ParseReference(host + "/foo/bar:latest")
+	got, err := remote.Image(pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := got.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url := manifest.Annotations["org.opencontainers.image.url"]; url != "{{ .SHA }}" {
+		t.Errorf("url annotation = %q, want the template rendered once", url)
+	}
+}
@@ -25,8 +25,9 @@ const (
 
 type (
 	Image struct {
-		Image  string `json:"image"`
-		Digest string `json:"digest"`
+		Image    string `json:"image"`
+		Digest   string `json:"digest"`
+		Platform string `json:"platform,omitempty"`
 	}
 	Data struct {
 		RegistryType RegistryTypeEnum `json:"registryType"`
@@ -47,6 +48,13 @@ func WritePluginArtifactFile(registryType RegistryTypeEnum, artifactFilePath, re
 			Digest: digest,
 		})
 	}
+	return WritePluginArtifactFileImages(registryType, artifactFilePath, registryUrl, images)
+}
+
+// WritePluginArtifactFileImages writes the given images to the artifact file.
+// It is used when the images do not share a single digest, e.g. for the
+// per-platform images of a multi-platform build.
+func WritePluginArtifactFileImages(registryType RegistryTypeEnum, artifactFilePath, registryUrl string, images []Image) error {
 	data := Data{
 		RegistryType: registryType,
 		RegistryUrl:  registryUrl,
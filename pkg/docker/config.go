@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/pkg/errors"
 )
@@ -18,19 +20,100 @@ const (
 
 type (
 	Auth struct {
-		Auth string `json:"auth"`
+		Auth          string `json:"auth,omitempty"`
+		IdentityToken string `json:"identitytoken,omitempty"`
+		// Extra holds the other fields of the entry, such as username and
+		// password, which are written back unchanged.
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 
 	Config struct {
 		Auths       map[string]Auth   `json:"auths"`
 		CredHelpers map[string]string `json:"credHelpers,omitempty"`
+		CredsStore  string            `json:"credsStore,omitempty"`
+		Proxies     json.RawMessage   `json:"proxies,omitempty"`
+		// Extra holds the other top-level fields of the config, such as
+		// HttpHeaders, which are written back unchanged.
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 )
 
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra.
+func (a *Auth) UnmarshalJSON(data []byte) error {
+	type auth Auth
+	if err := json.Unmarshal(data, (*auth)(a)); err != nil {
+		return err
+	}
+	extra, err := extraFields(data, "auth", "identitytoken")
+	a.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler, writing the fields of Extra back.
+func (a Auth) MarshalJSON() ([]byte, error) {
+	type auth Auth
+	return marshalWithExtra(auth(a), a.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type config Config
+	if err := json.Unmarshal(data, (*config)(c)); err != nil {
+		return err
+	}
+	extra, err := extraFields(data, "auths", "credHelpers", "credsStore", "proxies")
+	c.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler, writing the fields of Extra back.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type config Config
+	return marshalWithExtra(config(c), c.Extra)
+}
+
+// extraFields returns the fields of the JSON object other than the known
+// ones, or nil if there are none.
+func extraFields(data []byte, known ...string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, key := range known {
+		delete(fields, key)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// marshalWithExtra marshals v and adds the extra fields it does not set.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// RegistryCredentials are the credentials of a registry: a username with a
+// password or an identity token, or a credential helper.
 type RegistryCredentials struct {
-	Registry string
-	Username string
-	Password string
+	Registry      string `json:"registry"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identityToken,omitempty"`
+	CredHelper    string `json:"credHelper,omitempty"`
 }
 
 func NewConfig() *Config {
@@ -46,11 +129,122 @@ func (c *Config) SetAuth(registry, username, password string) {
 	c.Auths[registry] = Auth{Auth: encodedString}
 }
 
+// SetIdentityToken sets the identity token, an OAuth2 refresh token, of the
+// registry. The username is optional.
+func (c *Config) SetIdentityToken(registry, username, token string) {
+	auth := Auth{IdentityToken: token}
+	if username != "" {
+		auth.Auth = base64.StdEncoding.EncodeToString([]byte(username + ":"))
+	}
+	c.Auths[registry] = auth
+}
+
 func (c *Config) SetCredHelper(registry, helper string) {
 	c.CredHelpers[registry] = helper
 }
 
+// CreateDockerConfig adds the credentials to the config and merges it on top
+// of the existing config.json in dockerPath, see MergeDockerConfig.
 func (c *Config) CreateDockerConfig(credentials []RegistryCredentials, dockerPath string) error {
+	if err := c.AddCredentials(credentials); err != nil {
+		return err
+	}
+	return c.MergeDockerConfig(dockerPath)
+}
+
+// MergeDockerConfig merges the config on top of the existing config.json in
+// dockerPath, if any, and writes the result back. Entries of the existing
+// config for other registries, its credsStore, its proxies and the fields
+// the plugin does not know are preserved.
+func (c *Config) MergeDockerConfig(dockerPath string) error {
+	config, err := LoadConfig(dockerPath)
+	if err != nil {
+		return err
+	}
+	config.Merge(c)
+	jsonBytes, err := json.Marshal(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize docker config json")
+	}
+	if err := WriteDockerConfig(jsonBytes, dockerPath); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to write docker config to path: %s", dockerPath))
+	}
+	return nil
+}
+
+// LoadConfig reads the config.json in dockerPath. An empty config is returned
+// when the file does not exist.
+func LoadConfig(dockerPath string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dockerPath, "config.json"))
+	if os.IsNotExist(err) {
+		return NewConfig(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read docker config")
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses a docker config.json.
+func ParseConfig(data []byte) (*Config, error) {
+	c := NewConfig()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrap(err, "failed to parse docker config json")
+	}
+	if c.Auths == nil {
+		c.Auths = make(map[string]Auth)
+	}
+	if c.CredHelpers == nil {
+		c.CredHelpers = make(map[string]string)
+	}
+	return c, nil
+}
+
+// Merge merges other on top of the config. For a registry both configs hold
+// credentials for, the auth or credential helper of other replaces the one
+// of the config, including its identity token, so that a registry does not
+// end up with a credential helper shadowing its new auth. The credsStore,
+// proxies and other top-level fields of other replace those of the config
+// when set.
+//
+// Docker prefers a credsStore to the auths of the config file, so when the
+// merged config has a credsStore and other does not, the registries other
+// holds auths for are pinned to the config file with an empty credential
+// helper.
+func (c *Config) Merge(other *Config) {
+	for registry, auth := range other.Auths {
+		c.Auths[registry] = auth
+		delete(c.CredHelpers, registry)
+	}
+	for registry, helper := range other.CredHelpers {
+		c.CredHelpers[registry] = helper
+		if _, ok := other.Auths[registry]; !ok {
+			delete(c.Auths, registry)
+		}
+	}
+	if other.CredsStore != "" {
+		c.CredsStore = other.CredsStore
+	} else if c.CredsStore != "" {
+		for registry := range other.Auths {
+			if _, ok := other.CredHelpers[registry]; !ok {
+				c.CredHelpers[registry] = ""
+			}
+		}
+	}
+	if len(other.Proxies) > 0 {
+		c.Proxies = other.Proxies
+	}
+	for key, value := range other.Extra {
+		if c.Extra == nil {
+			c.Extra = make(map[string]json.RawMessage)
+		}
+		c.Extra[key] = value
+	}
+}
+
+// AddCredentials validates the credentials and adds them to the config
+// without writing it to disk.
+func (c *Config) AddCredentials(credentials []RegistryCredentials) error {
 	for _, cred := range credentials {
 		if cred.Registry != "" {
 			// update v2 docker registry to v1
@@ -60,6 +254,14 @@ func (c *Config) CreateDockerConfig(credentials []RegistryCredentials, dockerPat
 				cred.Registry = v1RegistryURL
 			}
 
+			if cred.CredHelper != "" {
+				c.SetCredHelper(cred.Registry, cred.CredHelper)
+				continue
+			}
+			if cred.IdentityToken != "" {
+				c.SetIdentityToken(cred.Registry, cred.Username, cred.IdentityToken)
+				continue
+			}
 			if cred.Username == "" {
 				return fmt.Errorf("Username must be specified for registry: %s", cred.Registry)
 			}
@@ -69,14 +271,28 @@ func (c *Config) CreateDockerConfig(credentials []RegistryCredentials, dockerPat
 			c.SetAuth(cred.Registry, cred.Username, cred.Password)
 		}
 	}
-	jsonBytes, err := json.Marshal(c)
-	if err != nil {
-		return errors.Wrap(err, "failed to serialize docker config json")
+	return nil
+}
+
+// Registries returns the sorted list of registries the config holds
+// credentials or credential helpers for.
+func (c *Config) Registries() []string {
+	seen := make(map[string]bool)
+	var registries []string
+	for registry := range c.Auths {
+		if !seen[registry] {
+			seen[registry] = true
+			registries = append(registries, registry)
+		}
 	}
-	if err := WriteDockerConfig(jsonBytes, dockerPath); err != nil {
-		return errors.Wrap(err, fmt.Sprintf("failed to write docker config to path: %s", dockerPath))
+	for registry := range c.CredHelpers {
+		if !seen[registry] {
+			seen[registry] = true
+			registries = append(registries, registry)
+		}
 	}
-	return nil
+	sort.Strings(registries)
+	return registries
 }
 
 func WriteDockerConfig(data []byte, path string) (string error) {
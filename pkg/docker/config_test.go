@@ -53,3 +53,116 @@ func TestConfig(t *testing.T) {
 	assert.Equal(t, c.Auths, configFromFile.Auths)
 	assert.Equal(t, c.CredHelpers, configFromFile.CredHelpers)
 }
+
+func TestConfigRegistries(t *testing.T) {
+	c := NewConfig()
+	err := c.AddCredentials([]RegistryCredentials{
+		{Registry: "gcr.io", Username: "user", Password: "pass"},
+		{Registry: RegistryV2, Username: "user", Password: "pass"},
+	})
+	assert.NoError(t, err)
+	c.SetCredHelper(RegistryECRPublic, "ecr-login")
+	c.SetCredHelper("gcr.io", "gcr")
+
+	assert.Equal(t, []string{"gcr.io", RegistryV1, RegistryECRPublic}, c.Registries())
+
+	err = c.AddCredentials([]RegistryCredentials{{Registry: "quay.io", Username: "user"}})
+	assert.Error(t, err)
+}
+
+func TestConfigMerge(t *testing.T) {
+	base, err := ParseConfig([]byte(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "b3JnOnB1bGw="},
+			"quay.io": {"auth": "cXVheTpwYXNz"},
+			"registry.example.com": {"identitytoken": "refresh-token"}
+		},
+		"credHelpers": {"gcr.io": "gcloud", "public.ecr.aws": "ecr-login"},
+		"credsStore": "desktop",
+		"proxies": {"default": {"httpProxy": "http://proxy.example.com:3128"}}
+	}`))
+	assert.NoError(t, err)
+
+	generated := NewConfig()
+	err = generated.AddCredentials([]RegistryCredentials{
+		{Registry: RegistryV1, Username: "ci", Password: "push"},
+		{Registry: "gcr.io", Username: "_json_key", Password: "{}"},
+	})
+	assert.NoError(t, err)
+	generated.SetCredHelper("quay.io", "quay")
+	base.Merge(generated)
+
+	assert.Equal(t, map[string]Auth{
+		RegistryV1:             {Auth: "Y2k6cHVzaA=="},
+		"gcr.io":               {Auth: "X2pzb25fa2V5Ont9"},
+		"registry.example.com": {IdentityToken: "refresh-token"},
+	}, base.Auths)
+	assert.Equal(t, map[string]string{
+		RegistryV1:        "",
+		"gcr.io":          "",
+		"quay.io":         "quay",
+		RegistryECRPublic: "ecr-login",
+	}, base.CredHelpers)
+	assert.Equal(t, "desktop", base.CredsStore)
+	assert.JSONEq(t, `{"default": {"httpProxy": "http://proxy.example.com:3128"}}`, string(base.Proxies))
+
+	_, err = ParseConfig([]byte(`{"auths": [`))
+	assert.Error(t, err)
+}
+
+func TestCreateDockerConfigMerge(t *testing.T) {
+	tempDir := t.TempDir()
+	existing := `{"auths":{"mirror.example.com":{"auth":"b3JnOnB1bGw="}},"proxies":{"default":{"noProxy":"localhost"}}}`
+	err := WriteDockerConfig([]byte(existing), tempDir)
+	assert.NoError(t, err)
+
+	err = NewConfig().CreateDockerConfig([]RegistryCredentials{{Registry: "gcr.io", Username: "user", Password: "pass"}}, tempDir)
+	assert.NoError(t, err)
+
+	data, err := ioutil.ReadFile(filepath.Join(tempDir, "config.json"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"auths": {
+			"gcr.io": {"auth": "dXNlcjpwYXNz"},
+			"mirror.example.com": {"auth": "b3JnOnB1bGw="}
+		},
+		"proxies": {"default": {"noProxy": "localhost"}}
+	}`, string(data))
+
+	c, err := LoadConfig(filepath.Join(tempDir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, c.Auths)
+}
+
+func TestMergeDockerConfigKeepsUnknownFields(t *testing.T) {
+	tempDir := t.TempDir()
+	override := `{
+		"auths": {
+			"registry.example.com": {"username": "user", "password": "pass", "email": "ci@example.com"},
+			"quay.io": {"auth": "cXVheTpwYXNz", "registrytoken": "token"}
+		},
+		"HttpHeaders": {"User-Agent": "Docker-Client/19.03.1 (linux)"},
+		"detachKeys": "ctrl-e,e"
+	}`
+	c, err := ParseConfig([]byte(override))
+	assert.NoError(t, err)
+	err = c.MergeDockerConfig(tempDir)
+	assert.NoError(t, err)
+
+	data, err := ioutil.ReadFile(filepath.Join(tempDir, "config.json"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, override, string(data))
+
+	err = NewConfig().CreateDockerConfig([]RegistryCredentials{{Registry: "quay.io", Username: "user", Password: "pass"}}, tempDir)
+	assert.NoError(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(tempDir, "config.json"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"auths": {
+			"registry.example.com": {"username": "user", "password": "pass", "email": "ci@example.com"},
+			"quay.io": {"auth": "dXNlcjpwYXNz"}
+		},
+		"HttpHeaders": {"User-Agent": "Docker-Client/19.03.1 (linux)"},
+		"detachKeys": "ctrl-e,e"
+	}`, string(data))
+}
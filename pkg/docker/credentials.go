@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dockerHubHosts are the hosts of Docker Hub, whose credentials are stored
+// under the v1 registry URL.
+var dockerHubHosts = map[string]bool{
+	"docker.io":               true,
+	"index.docker.io":         true,
+	"registry-1.docker.io":    true,
+	"registry.hub.docker.com": true,
+}
+
+// ParseRegistryCredentials parses a JSON array of registry credentials, e.g.
+// [{"registry": "ghcr.io", "username": "octocat", "password": "..."}]. Each
+// entry must hold exactly one of a password, which requires a username, an
+// identity token or a credential helper. Registries are normalized to their
+// host, Docker Hub to the v1 registry URL, and when a registry is listed more
+// than once the last entry is used.
+func ParseRegistryCredentials(data string) ([]RegistryCredentials, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+	var entries []RegistryCredentials
+	decoder := json.NewDecoder(bytes.NewBufferString(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, errors.Wrap(err, "failed to parse registry credentials json")
+	}
+
+	var credentials []RegistryCredentials
+	index := make(map[string]int)
+	for i, cred := range entries {
+		registry := normalizeRegistry(cred.Registry)
+		if registry == "" {
+			return nil, fmt.Errorf("Registry must be specified for registry credentials at index %d", i)
+		}
+		if err := validateRegistryCredentials(cred); err != nil {
+			return nil, fmt.Errorf("%v for registry: %s", err, cred.Registry)
+		}
+		cred.Registry = registry
+		if j, ok := index[registry]; ok {
+			fmt.Printf("Registry credentials for %s are defined more than once, using the last ones\n", registry)
+			credentials[j] = cred
+			continue
+		}
+		index[registry] = len(credentials)
+		credentials = append(credentials, cred)
+	}
+	return credentials, nil
+}
+
+// validateRegistryCredentials checks that the credentials hold exactly one
+// of a password, an identity token or a credential helper.
+func validateRegistryCredentials(cred RegistryCredentials) error {
+	set := 0
+	for _, value := range []string{cred.Password, cred.IdentityToken, cred.CredHelper} {
+		if value != "" {
+			set++
+		}
+	}
+	switch {
+	case set != 1:
+		return fmt.Errorf("Exactly one of password, identityToken or credHelper must be specified")
+	case cred.Password != "" && cred.Username == "":
+		return fmt.Errorf("Username must be specified with password")
+	case cred.CredHelper != "" && cred.Username != "":
+		return fmt.Errorf("Username cannot be used with credHelper")
+	}
+	return nil
+}
+
+// normalizeRegistry returns the lowercase host of the registry, without the
+// scheme and path, or the v1 registry URL for Docker Hub.
+func normalizeRegistry(registry string) string {
+	host := strings.ToLower(strings.TrimSpace(registry))
+	if _, after, ok := strings.Cut(host, "://"); ok {
+		host = after
+	}
+	host, _, _ = strings.Cut(host, "/")
+	if dockerHubHosts[host] {
+		return v1RegistryURL
+	}
+	return host
+}
+
+// AppendRegistries appends the registries of the credentials that are not
+// listed yet, as AddCredentials adds them to a config. The credentials are
+// not validated, so that dry runs can list registries whose password is only
+// fetched by the build.
+func AppendRegistries(registries []string, credentials []RegistryCredentials) []string {
+	seen := make(map[string]bool)
+	for _, registry := range registries {
+		seen[registry] = true
+	}
+	for _, cred := range credentials {
+		registry := cred.Registry
+		if registry == v2RegistryURL || registry == v2HubRegistryURL {
+			registry = v1RegistryURL
+		}
+		if registry != "" && !seen[registry] {
+			seen[registry] = true
+			registries = append(registries, registry)
+		}
+	}
+	return registries
+}
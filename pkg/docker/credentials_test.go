@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegistryCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []RegistryCredentials
+		wantErr string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "password, identity token and credential helper",
+			data: `[
+				{"registry": "https://GHCR.io/v2/", "username": "octocat", "password": "ghp_token"},
+				{"registry": "harbor.example.com", "identityToken": "refresh-token"},
+				{"registry": "123456789012.dkr.ecr.us-east-1.amazonaws.com", "credHelper": "ecr-login"}
+			]`,
+			want: []RegistryCredentials{
+				{Registry: "ghcr.io", Username: "octocat", Password: "ghp_token"},
+				{Registry: "harbor.example.com", IdentityToken: "refresh-token"},
+				{Registry: "123456789012.dkr.ecr.us-east-1.amazonaws.com", CredHelper: "ecr-login"},
+			},
+		},
+		{
+			name: "duplicate docker hub hosts",
+			data: `[
+				{"registry": "docker.io", "username": "first", "password": "pass"},
+				{"registry": "quay.io", "username": "quay", "password": "pass"},
+				{"registry": "https://index.docker.io/v1/", "username": "last", "password": "pass"}
+			]`,
+			want: []RegistryCredentials{
+				{Registry: RegistryV1, Username: "last", Password: "pass"},
+				{Registry: "quay.io", Username: "quay", Password: "pass"},
+			},
+		},
+		{
+			name:    "invalid json",
+			data:    `{"registry": "ghcr.io"}`,
+			wantErr: "failed to parse registry credentials json",
+		},
+		{
+			name:    "unknown field",
+			data:    `[{"registry": "ghcr.io", "identity_token": "refresh-token"}]`,
+			wantErr: "unknown field",
+		},
+		{
+			name:    "missing registry",
+			data:    `[{"username": "octocat", "password": "pass"}]`,
+			wantErr: "Registry must be specified",
+		},
+		{
+			name:    "missing secret",
+			data:    `[{"registry": "ghcr.io", "username": "octocat"}]`,
+			wantErr: "Exactly one of",
+		},
+		{
+			name:    "password and credential helper",
+			data:    `[{"registry": "ghcr.io", "username": "octocat", "password": "pass", "credHelper": "gh"}]`,
+			wantErr: "Exactly one of",
+		},
+		{
+			name:    "password without username",
+			data:    `[{"registry": "ghcr.io", "password": "pass"}]`,
+			wantErr: "Username must be specified",
+		},
+		{
+			name:    "credential helper with username",
+			data:    `[{"registry": "ghcr.io", "username": "octocat", "credHelper": "gh"}]`,
+			wantErr: "Username cannot be used",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegistryCredentials(tt.data)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAddCredentialsIdentityTokenAndCredHelper(t *testing.T) {
+	c := NewConfig()
+	err := c.AddCredentials([]RegistryCredentials{
+		{Registry: "harbor.example.com", Username: "robot", IdentityToken: "refresh-token"},
+		{Registry: "ghcr.io", IdentityToken: "refresh-token"},
+		{Registry: "gcr.io", CredHelper: "gcloud"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Auth{
+		"harbor.example.com": {Auth: "cm9ib3Q6", IdentityToken: "refresh-token"},
+		"ghcr.io":            {IdentityToken: "refresh-token"},
+	}, c.Auths)
+	assert.Equal(t, map[string]string{"gcr.io": "gcloud"}, c.CredHelpers)
+
+	registries := AppendRegistries([]string{"gcr.io", RegistryV1}, []RegistryCredentials{
+		{Registry: "ghcr.io"},
+		{Registry: "gcr.io"},
+		{Registry: ""},
+		{Registry: "https://index.docker.io/v2/"},
+		{Registry: "quay.io", Username: "robot"},
+	})
+	assert.Equal(t, []string{"gcr.io", RegistryV1, "ghcr.io", "quay.io"}, registries)
+}
@@ -0,0 +1,141 @@
+// Package dockerignore matches build context paths against the patterns of
+// a .dockerignore file.
+package dockerignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches slash separated paths, relative to the build context,
+// against .dockerignore patterns.
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re        *regexp.Regexp
+	exclusion bool // pattern starts with "!" and re-includes matching paths
+}
+
+// ReadFile returns the matcher for the .dockerignore file at path. A missing
+// file yields a matcher that ignores nothing.
+func ReadFile(path string) (*Matcher, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return New(lines)
+}
+
+// New returns the matcher for the given .dockerignore lines. Empty lines and
+// comments are skipped.
+func New(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p pattern
+		if strings.HasPrefix(line, "!") {
+			p.exclusion = true
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/")
+		re, err := compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid .dockerignore pattern %q: %v", line, err)
+		}
+		p.re = re
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// HasExclusions returns true if a pattern re-includes paths, in which case
+// the children of an ignored directory may still be part of the context.
+func (m *Matcher) HasExclusions() bool {
+	for _, p := range m.patterns {
+		if p.exclusion {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the path is ignored. A path is matched by a
+// pattern that matches the path itself or any of its parent directories, and
+// the last matching pattern wins.
+func (m *Matcher) Matches(path string) bool {
+	parents := strings.Split(path, "/")
+	matched := false
+	for _, p := range m.patterns {
+		for i := range parents {
+			if p.re.MatchString(strings.Join(parents[:i+1], "/")) {
+				matched = !p.exclusion
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// compile translates a pattern into a regular expression. "*" and "?" do not
+// match "/", while "**" matches any number of directories.
+func compile(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				i++
+				sb.WriteString("(.*/)?")
+			} else {
+				sb.WriteString(".*")
+			}
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,68 @@
+package dockerignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{name: "no_patterns", path: "main.go", want: false},
+		{name: "exact", patterns: []string{"README.md"}, path: "README.md", want: true},
+		{name: "leading_slash", patterns: []string{"/docs"}, path: "docs", want: true},
+		{name: "parent_directory", patterns: []string{"docs"}, path: "docs/guide/index.md", want: true},
+		{name: "star_single_level", patterns: []string{"*.md"}, path: "docs/index.md", want: false},
+		{name: "star_top_level", patterns: []string{"*.md"}, path: "index.md", want: true},
+		{name: "double_star", patterns: []string{"**/*.md"}, path: "docs/guide/index.md", want: true},
+		{name: "double_star_top_level", patterns: []string{"**/*.md"}, path: "index.md", want: true},
+		{name: "question_mark", patterns: []string{"file?.txt"}, path: "file1.txt", want: true},
+		{name: "character_class", patterns: []string{"file[0-9].txt"}, path: "filea.txt", want: false},
+		{name: "exclusion", patterns: []string{"*.md", "!README.md"}, path: "README.md", want: false},
+		{name: "exclusion_order", patterns: []string{"!README.md", "*.md"}, path: "README.md", want: true},
+		{name: "comment", patterns: []string{"# main.go"}, path: "main.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.patterns)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := m.Matches(tt.path); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := ReadFile(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		t.Fatalf("Unexpected error for missing file: %v", err)
+	}
+	if m.Matches("main.go") {
+		t.Errorf("missing .dockerignore must not ignore anything")
+	}
+
+	path := filepath.Join(dir, ".dockerignore")
+	if err := os.WriteFile(path, []byte("# docs\ndocs\n\n!docs/keep.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !m.HasExclusions() {
+		t.Errorf("HasExclusions() = false, want true")
+	}
+	if !m.Matches("docs/index.md") || m.Matches("docs/keep.md") {
+		t.Errorf("Unexpected matches for %s", path)
+	}
+}
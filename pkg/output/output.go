@@ -1,10 +1,18 @@
 package output
 
 import (
+	"strings"
+
 	"github.com/joho/godotenv"
 )
 
 func WritePluginOutputFile(outputFilePath, digest string, pluginTarPath string) error {
+	return WritePluginOutputFileTags(outputFilePath, digest, pluginTarPath, nil)
+}
+
+// WritePluginOutputFileTags writes the plugin output file, reporting the
+// tags the image was published to as a comma separated list.
+func WritePluginOutputFileTags(outputFilePath, digest, pluginTarPath string, tags []string) error {
 	output := make(map[string]string)
 	if digest != "" {
 		output["digest"] = digest
@@ -14,5 +22,9 @@ func WritePluginOutputFile(outputFilePath, digest string, pluginTarPath string)
 		output["IMAGE_TAR_PATH"] = pluginTarPath
 	}
 
+	if len(tags) > 0 {
+		output["tags"] = strings.Join(tags, ",")
+	}
+
 	return godotenv.Write(output, outputFilePath)
 }
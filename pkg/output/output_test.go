@@ -3,6 +3,7 @@ package output
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -143,3 +144,19 @@ func TestWritePluginOutputFile(t *testing.T) {
 func contains(content, substring string) bool {
 	return len(substring) > 0 && content != "" && content != "\n" && content != "\r\n"
 }
+
+func TestWritePluginOutputFileTags(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "output.env")
+	if err := WritePluginOutputFileTags(outputPath, "sha256:test", "", []string{"1", "1.2", "1.2.3"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	for _, want := range []string{`digest="sha256:test"`, `tags="1,1.2,1.2.3"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected %s in output file, got %s", want, content)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package tagger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tagging schemes of auto-tag and expand-tag.
+const (
+	SchemeSemver = "semver" // MAJOR.MINOR.PATCH, floating MAJOR and MAJOR.MINOR tags
+	SchemeCalVer = "calver" // YYYY.MM.MICRO or YYYY.0M.0D, floating YYYY and YYYY.MM tags
+)
+
+// ValidScheme returns an error if the tagging scheme is not
+// known, an empty scheme is semver.
+func ValidScheme(scheme string) error {
+	switch scheme {
+	case "", SchemeSemver, SchemeCalVer:
+		return nil
+	}
+	return fmt.Errorf("unknown tagging scheme %q, expected %s or %s", scheme, SchemeSemver, SchemeCalVer)
+}
+
+// Policy expands a version into the tags of an image. Both
+// auto-tag and expand-tag follow its rules:
+//
+//   - A leading v is stripped. Underscores are replaced with
+//     dashes, as semantic versions do not allow them.
+//   - A semantic version needs MAJOR.MINOR.PATCH. With
+//     Shorthand, as for expand-tag, MAJOR and MAJOR.MINOR are
+//     completed with zeros. Git tags are never shorthand, as a
+//     tag such as 20190203 is not a release.
+//   - Leading zeros are kept as written: 18.06.0 is tagged
+//     18, 18.06 and 18.06.0.
+//   - A release is tagged with its major, major.minor and full
+//     version. Major version zero has no major tag, as 0.x
+//     releases are not compatible with each other, unless
+//     MajorZero is set as for expand-tag: 0.9.1 is then also
+//     tagged 0.
+//   - A pre-release is only tagged with its full version,
+//     unless its first identifier, without trailing digits,
+//     names one of the Channels: 1.5.0-rc.2 is then also tagged
+//     with the floating 1.5-rc and rc channel tags.
+//   - Build metadata is carried through to every tag with an
+//     underscore, as tags do not allow a plus sign, e.g.
+//     1_linux-amd64, 1.2_linux-amd64 and 1.2.3_linux-amd64.
+//   - The suffix is appended to every tag with a dash, and
+//     replaces the latest tag.
+//   - With Floating, the major and minor tags are only included
+//     when no higher release of their series exists among the
+//     Existing tags. Channel tags are only included when no
+//     higher pre-release of the same channel exists in their
+//     series. Only existing tags with the same suffix and build
+//     metadata are compared.
+//   - With the calver scheme, the year and year.month tags
+//     float instead of the major and minor tags, and versions
+//     with a modifier are only tagged with their full version.
+type Policy struct {
+	Scheme    string   // semver (default) or calver
+	Shorthand bool     // accept MAJOR and MAJOR.MINOR semantic versions
+	MajorZero bool     // tag releases of major version zero with 0
+	Suffix    string   // suffix appended to every tag
+	Floating  bool     // only move floating tags when the version is the highest of their series
+	Existing  []string // tags of the repository, compared when Floating is set
+	Channels  []string // pre-release channels tagged with floating channel tags
+}
+
+// DefaultChannels are the pre-release channels tagged when
+// channel tags are enabled without a list of channels.
+var DefaultChannels = []string{"rc", "beta", "alpha"}
+
+// Floating reports which floating tags may be moved to a
+// release version.
+type Floating struct {
+	Latest bool // no higher release exists
+	Major  bool // no higher release of the same major version (year) exists
+	Minor  bool // no higher release of the same major and minor version (year and month) exists
+}
+
+// Parse parses the version following the scheme.
+func (p Policy) Parse(version string) (*Version, error) {
+	if p.Scheme == SchemeCalVer {
+		return ParseCalVer(version)
+	}
+	return ParseSemver(version, p.Shorthand)
+}
+
+// Expand returns the tags of the version.
+func (p Policy) Expand(version string) ([]string, error) {
+	v, err := p.Parse(version)
+	if err != nil {
+		return nil, err
+	}
+	build := ""
+	if v.Build != "" {
+		build = "_" + v.Build
+	}
+
+	if v.PreRelease != "" {
+		channel := p.channel(v)
+		if channel == "" {
+			return p.withSuffix([]string{v.tag()}), nil
+		}
+		series, latest := p.channelFloating(v, channel)
+		var tags []string
+		if series {
+			tags = append(tags, v.parts[0]+"."+v.parts[1]+"-"+channel+build)
+		}
+		if latest {
+			tags = append(tags, channel+build)
+		}
+		return p.withSuffix(append(tags, v.tag())), nil
+	}
+	calver := p.Scheme == SchemeCalVer
+	f := p.FloatingTags(v)
+
+	var tags []string
+	if f.Major && (calver || p.MajorZero || v.Major != 0) {
+		tags = append(tags, v.parts[0]+build)
+	}
+	if f.Minor && (!calver || v.Patch >= 0) {
+		tags = append(tags, v.parts[0]+"."+v.parts[1]+build)
+	}
+	tags = append(tags, v.tag())
+	return p.withSuffix(tags), nil
+}
+
+// AutoTags returns the tags of the commit ref: the expanded
+// version of a tag ref, latest otherwise.
+func (p Policy) AutoTags(ref string) ([]string, error) {
+	if !strings.HasPrefix(ref, "refs/tags/") {
+		return p.withSuffix([]string{"latest"}), nil
+	}
+	p.Shorthand = false
+	return p.Expand(stripTagPrefix(ref))
+}
+
+// MoveLatest returns false if latest must not be moved to the
+// version, because a higher release exists among the Existing
+// tags. Tags that are not releases never prevent it.
+func (p Policy) MoveLatest(version string) bool {
+	p.Shorthand = false
+	v, err := p.Parse(version)
+	if err != nil || v.PreRelease != "" {
+		return true
+	}
+	return p.FloatingTags(v).Latest
+}
+
+// FloatingTags compares the release with the Existing tags and
+// reports which floating tags may be moved to it. Every tag
+// may be moved unless Floating is set.
+func (p Policy) FloatingTags(v *Version) Floating {
+	f := Floating{Latest: true, Major: true, Minor: true}
+	if !p.Floating {
+		return f
+	}
+	for _, e := range p.existing() {
+		if e.PreRelease != "" || e.Build != v.Build || e.Compare(v) <= 0 {
+			continue
+		}
+		f.Latest = false
+		if e.Major == v.Major {
+			f.Major = false
+			if e.Minor == v.Minor {
+				f.Minor = false
+			}
+		}
+	}
+	return f
+}
+
+// channel returns the channel of the pre-release, or an empty
+// string if it is not one of the Channels.
+func (p Policy) channel(v *Version) string {
+	first := strings.SplitN(v.PreRelease, ".", 2)[0]
+	name := strings.TrimRight(first, "0123456789")
+	for _, channel := range p.Channels {
+		if name != "" && strings.EqualFold(name, channel) {
+			return name
+		}
+	}
+	return ""
+}
+
+// channelFloating compares the pre-release with the Existing
+// tags of the same channel and reports whether the series and
+// the channel tags may be moved to it.
+func (p Policy) channelFloating(v *Version, channel string) (series, latest bool) {
+	series, latest = true, true
+	if !p.Floating {
+		return
+	}
+	for _, e := range p.existing() {
+		if e.PreRelease == "" || p.channel(e) != channel || e.Build != v.Build || e.Compare(v) <= 0 {
+			continue
+		}
+		latest = false
+		if e.Major == v.Major && e.Minor == v.Minor {
+			series = false
+		}
+	}
+	return
+}
+
+// existing returns the versions of the Existing tags with the
+// suffix. Floating tags and other tags are skipped. The first
+// underscore of a tag separates its build metadata, as other
+// underscores are replaced when the tags are expanded.
+func (p Policy) existing() []*Version {
+	p.Shorthand = false
+	var versions []*Version
+	for _, tag := range p.Existing {
+		if len(p.Suffix) != 0 {
+			version := strings.TrimSuffix(tag, "-"+p.Suffix)
+			if version == tag {
+				continue
+			}
+			tag = version
+		}
+		if v, err := p.Parse(strings.Replace(tag, "_", "+", 1)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+func (p Policy) withSuffix(tags []string) []string {
+	return withSuffix(tags, p.Suffix)
+}
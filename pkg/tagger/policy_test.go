@@ -0,0 +1,190 @@
+package tagger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		version string
+		want    []string
+		wantErr bool
+	}{
+		// releases
+		{name: "release", version: "v1.2.3", want: []string{"1", "1.2", "1.2.3"}},
+		{name: "major zero", version: "0.9.1", want: []string{"0.9", "0.9.1"}},
+		{name: "major zero tagged", policy: Policy{MajorZero: true}, version: "v0.9.1", want: []string{"0", "0.9", "0.9.1"}},
+		{name: "leading zeros", version: "v18.06.0", want: []string{"18", "18.06", "18.06.0"}},
+		{name: "shorthand", policy: Policy{Shorthand: true}, version: "v1.2", want: []string{"1", "1.2", "1.2.0"}},
+		{name: "shorthand not allowed", version: "v1.2", wantErr: true},
+
+		// pre-releases
+		{name: "prerelease", version: "v1.2.3-rc.1", want: []string{"1.2.3-rc.1"}},
+		{name: "prerelease with build", version: "v1.2.3-rc1+bld", want: []string{"1.2.3-rc1_bld"}},
+
+		// pre-release channels
+		{name: "channel", policy: Policy{Channels: DefaultChannels}, version: "v1.5.0-rc.2", want: []string{"1.5-rc", "rc", "1.5.0-rc.2"}},
+		{name: "channel without separator", policy: Policy{Channels: DefaultChannels}, version: "v1.5.0-beta2", want: []string{"1.5-beta", "beta", "1.5.0-beta2"}},
+		{name: "channel with build", policy: Policy{Channels: DefaultChannels}, version: "v1.5.0-rc.2+bld", want: []string{"1.5-rc_bld", "rc_bld", "1.5.0-rc.2_bld"}},
+		{name: "custom channel", policy: Policy{Channels: []string{"nightly"}}, version: "v1.5.0-nightly.20261017", want: []string{"1.5-nightly", "nightly", "1.5.0-nightly.20261017"}},
+		{name: "unknown channel", policy: Policy{Channels: DefaultChannels}, version: "v1.5.0-nightly.1", want: []string{"1.5.0-nightly.1"}},
+		{name: "numeric prerelease", policy: Policy{Channels: DefaultChannels}, version: "v1.5.0-1", want: []string{"1.5.0-1"}},
+		{name: "channel suffix", policy: Policy{Channels: DefaultChannels, Suffix: "nanoserver"}, version: "v1.5.0-rc.2", want: []string{"1.5-rc-nanoserver", "rc-nanoserver", "1.5.0-rc.2-nanoserver"}},
+		{name: "calver channel", policy: Policy{Scheme: SchemeCalVer, Channels: DefaultChannels}, version: "2026.10.3-rc1", want: []string{"2026.10-rc", "rc", "2026.10.3-rc1"}},
+		{
+			name:    "channel floating highest",
+			policy:  Policy{Channels: DefaultChannels, Floating: true, Existing: []string{"1.5.0-rc.1", "1.5.0-beta.9", "1.4.0"}},
+			version: "1.5.0-rc.2",
+			want:    []string{"1.5-rc", "rc", "1.5.0-rc.2"},
+		},
+		{
+			name:    "channel floating older series",
+			policy:  Policy{Channels: DefaultChannels, Floating: true, Existing: []string{"1.6.0-rc.1"}},
+			version: "1.5.1-rc.1",
+			want:    []string{"1.5-rc", "1.5.1-rc.1"},
+		},
+		{
+			name:    "channel floating older pre-release",
+			policy:  Policy{Channels: DefaultChannels, Floating: true, Existing: []string{"1.5.0-rc.10"}},
+			version: "1.5.0-rc.2",
+			want:    []string{"1.5.0-rc.2"},
+		},
+
+		// build metadata
+		{name: "build", version: "v1.2.3+linux_amd64", want: []string{"1_linux-amd64", "1.2_linux-amd64", "1.2.3_linux-amd64"}},
+		{name: "build with dots", version: "v1.2.3+build.7", want: []string{"1_build.7", "1.2_build.7", "1.2.3_build.7"}},
+		{name: "shorthand with build", policy: Policy{Shorthand: true}, version: "v1+bld", wantErr: true},
+
+		// suffixes
+		{name: "suffix", policy: Policy{Suffix: "linux-amd64"}, version: "v1.2.3", want: []string{"1-linux-amd64", "1.2-linux-amd64", "1.2.3-linux-amd64"}},
+		{name: "prerelease suffix", policy: Policy{Suffix: "nanoserver"}, version: "v1.2.3-rc.1", want: []string{"1.2.3-rc.1-nanoserver"}},
+
+		// floating tags
+		{
+			name:    "floating highest",
+			policy:  Policy{Floating: true, Existing: []string{"latest", "1", "1.4", "1.4.8", "0.9.0"}},
+			version: "1.4.9",
+			want:    []string{"1", "1.4", "1.4.9"},
+		},
+		{
+			name:    "floating hotfix of older minor",
+			policy:  Policy{Floating: true, Existing: []string{"1.5.0", "2.0.0-rc.1"}},
+			version: "1.4.9",
+			want:    []string{"1.4", "1.4.9"},
+		},
+		{
+			name:    "floating older patch",
+			policy:  Policy{Floating: true, Existing: []string{"1.4.10"}},
+			version: "1.4.9",
+			want:    []string{"1.4.9"},
+		},
+		{
+			name:    "floating leading zeros",
+			policy:  Policy{Floating: true, Existing: []string{"18.6.1"}},
+			version: "18.06.0",
+			want:    []string{"18.06.0"},
+		},
+		{
+			name:    "floating compares the same suffix",
+			policy:  Policy{Floating: true, Suffix: "linux-amd64", Existing: []string{"1.5.0-linux-amd64", "1.5.1", "1.4.10-nanoserver"}},
+			version: "1.4.9",
+			want:    []string{"1.4-linux-amd64", "1.4.9-linux-amd64"},
+		},
+		{
+			name:    "floating compares the same build metadata",
+			policy:  Policy{Floating: true, Existing: []string{"1.5.0_linux-amd64", "1.4.10_linux-arm64", "1.4.10"}},
+			version: "1.4.9+linux_arm64",
+			want:    []string{"1.4.9_linux-arm64"},
+		},
+		{
+			name:    "floating without existing tags",
+			policy:  Policy{Existing: []string{"2.0.0"}},
+			version: "1.4.9",
+			want:    []string{"1", "1.4", "1.4.9"},
+		},
+
+		// calendar versions
+		{name: "calver", policy: Policy{Scheme: SchemeCalVer}, version: "2026.10.3", want: []string{"2026", "2026.10", "2026.10.3"}},
+		{name: "calver zero padded", policy: Policy{Scheme: SchemeCalVer}, version: "2026.01.05", want: []string{"2026", "2026.01", "2026.01.05"}},
+		{name: "calver year month", policy: Policy{Scheme: SchemeCalVer}, version: "2026.10", want: []string{"2026", "2026.10"}},
+		{name: "calver modifier", policy: Policy{Scheme: SchemeCalVer}, version: "2026.10.3-rc1", want: []string{"2026.10.3-rc1"}},
+		{name: "calver semver", policy: Policy{Scheme: SchemeCalVer}, version: "1.2.3", wantErr: true},
+		{
+			name:    "calver floating",
+			policy:  Policy{Scheme: SchemeCalVer, Floating: true, Existing: []string{"2026.10.0", "2026.11.0-rc1"}},
+			version: "2026.09.4",
+			want:    []string{"2026.09", "2026.09.4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Expand(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expect error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAutoTags(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		ref    string
+		want   []string
+	}{
+		{ref: "refs/heads/master", want: []string{"latest"}},
+		{policy: Policy{Suffix: "linux-amd64"}, ref: "refs/heads/master", want: []string{"linux-amd64"}},
+		{ref: "refs/tags/v1.4.9+linux_amd64", want: []string{"1_linux-amd64", "1.4_linux-amd64", "1.4.9_linux-amd64"}},
+		{policy: Policy{Shorthand: true}, ref: "refs/tags/v1", want: nil},
+		{policy: Policy{Scheme: SchemeCalVer}, ref: "refs/tags/2026.10.3", want: []string{"2026", "2026.10", "2026.10.3"}},
+	}
+	for _, tt := range tests {
+		got, err := tt.policy.AutoTags(tt.ref)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Expect error for %s, got %v", tt.ref, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tt.ref, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AutoTags(%q) = %v, want %v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyMoveLatest(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		version string
+		want    bool
+	}{
+		{policy: Policy{Existing: []string{"2.0.0"}}, version: "1.4.9", want: true},
+		{policy: Policy{Floating: true, Existing: []string{"2.0.0"}}, version: "1.4.9", want: false},
+		{policy: Policy{Floating: true, Existing: []string{"2.0.0"}}, version: "2.0.1", want: true},
+		{policy: Policy{Floating: true, Existing: []string{"2.0.0"}}, version: "1.4", want: true},
+		{policy: Policy{Floating: true, Existing: []string{"2.0.0"}}, version: "1.5.0-rc.1", want: true},
+		{policy: Policy{Floating: true, Existing: []string{"2.0.0"}}, version: "latest", want: true},
+		{policy: Policy{Scheme: SchemeCalVer, Floating: true, Existing: []string{"2026.10.0"}}, version: "2026.09.4", want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.MoveLatest(tt.version); got != tt.want {
+			t.Errorf("MoveLatest(%q) with %v = %v, want %v", tt.version, tt.policy.Existing, got, tt.want)
+		}
+	}
+}
@@ -3,21 +3,48 @@
 package tagger
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path"
+	"regexp"
 	"strings"
-
-	"github.com/coreos/go-semver/semver"
 )
 
 // AutoTagsSuffix returns a set of default suggested tags
 // based on the commit ref with an attached suffix.
 func AutoTagsSuffix(ref, suffix string) ([]string, error) {
-	tags, err := AutoTags(ref)
-	if err != nil {
-		return nil, err
+	return Policy{Suffix: suffix}.AutoTags(ref)
+}
+
+// BranchTagsSuffix returns the sanitized branch name of a
+// branch ref as tag, with an attached suffix. It returns
+// false if the ref is not a branch ref.
+func BranchTagsSuffix(ref, suffix string) ([]string, bool) {
+	if !strings.HasPrefix(ref, "refs/heads/") {
+		return nil, false
+	}
+	tag := SanitizeTag(stripHeadPrefix(ref))
+	if tag == "" {
+		return nil, false
+	}
+	return withSuffix([]string{tag}, suffix), true
+}
+
+// PullRequestTagsSuffix returns the pr-<number> tag of a
+// pull request ref, with an attached suffix. It returns
+// false if the ref is not a pull request ref.
+func PullRequestTagsSuffix(ref, suffix string) ([]string, bool) {
+	number, ok := pullRequestNumber(ref)
+	if !ok {
+		return nil, false
 	}
+	return withSuffix([]string{"pr-" + number}, suffix), true
+}
+
+func withSuffix(tags []string, suffix string) []string {
 	if len(suffix) == 0 {
-		return tags, nil
+		return tags
 	}
 	for i, tag := range tags {
 		if tag == "latest" {
@@ -26,51 +53,103 @@ func AutoTagsSuffix(ref, suffix string) ([]string, error) {
 			tags[i] = fmt.Sprintf("%s-%s", tag, suffix)
 		}
 	}
-	return tags, nil
+	return tags
 }
 
-func splitOff(input string, delim string) string {
-	parts := strings.SplitN(input, delim, 2)
+// pullRequestRef matches the refs of GitHub and Gitea pull
+// requests and GitLab merge requests.
+var pullRequestRef = regexp.MustCompile(`^refs/(?:pull|merge-requests)/([0-9]+)/(?:head|merge)$`)
 
-	if len(parts) == 2 {
-		return parts[0]
+func pullRequestNumber(ref string) (string, bool) {
+	m := pullRequestRef.FindStringSubmatch(ref)
+	if m == nil {
+		return "", false
 	}
+	return m[1], true
+}
+
+// maxTagLength is the maximum length of a docker tag.
+const maxTagLength = 128
+
+var (
+	invalidTagChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+	repeatedDashes  = regexp.MustCompile(`-{2,}`)
+)
 
-	return input
+// SanitizeTag turns a name, such as a branch name, into a
+// valid docker tag: it is lowercased, every run of invalid
+// characters becomes a dash, and leading dots and dashes are
+// removed. Names longer than 128 characters are truncated and
+// suffixed with a hash of the name, to keep them unique.
+func SanitizeTag(name string) string {
+	tag := invalidTagChars.ReplaceAllString(strings.ToLower(name), "-")
+	tag = repeatedDashes.ReplaceAllString(tag, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	tag = strings.TrimRight(tag, "-")
+	if len(tag) <= maxTagLength {
+		return tag
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return strings.TrimRight(tag[:maxTagLength-len(hash)-1], ".-") + "-" + hash
 }
 
 // AutoTags returns a set of default suggested tags based on
-// the commit ref.
+// the commit ref. See Policy for the tagging rules.
 func AutoTags(ref string) ([]string, error) {
-	if !strings.HasPrefix(ref, "refs/tags/") {
-		return []string{"latest"}, nil
-	}
-	v := stripTagPrefix(ref)
-	version, err := semver.NewVersion(v)
+	tags, err := Policy{}.AutoTags(ref)
 	if err != nil {
 		return []string{"latest"}, err
 	}
-	if version.PreRelease != "" || version.Metadata != "" {
-		return []string{
-			version.String(),
-		}, nil
+	return tags, nil
+}
+
+// StripComponentPrefix strips the component prefix of a
+// monorepo tag ref, such as refs/tags/payments-api/v2.3.1 or
+// refs/tags/payments-api-v2.3.1, where the component before
+// the "/" or "-" separator matches the glob pattern. It returns
+// false if the tag belongs to another component. Other refs are
+// returned unchanged.
+func StripComponentPrefix(ref, pattern string) (string, bool, error) {
+	if pattern == "" || !strings.HasPrefix(ref, "refs/tags/") {
+		return ref, true, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", false, fmt.Errorf("invalid component pattern %q: %v", pattern, err)
 	}
 
-	v = stripTagPrefix(ref)
-	v = splitOff(splitOff(v, "+"), "-")
-	dotParts := strings.SplitN(v, ".", 3)
+	tag := strings.TrimPrefix(ref, "refs/tags/")
+	version := ""
+	for i := 0; i < len(tag); i++ {
+		if tag[i] != '/' && tag[i] != '-' {
+			continue
+		}
+		if matched, _ := path.Match(pattern, tag[:i]); !matched {
+			continue
+		}
+		// the component name itself may contain dashes, so a
+		// dash only separates the component from a valid version
+		if isVersion(tag[i+1:]) {
+			return "refs/tags/" + tag[i+1:], true, nil
+		}
+		if tag[i] == '/' {
+			version = tag[i+1:]
+		}
+	}
+	if version == "" {
+		return "", false, nil
+	}
+	return "refs/tags/" + version, true, nil
+}
 
-	if version.Major == 0 {
-		return []string{
-			fmt.Sprintf("%0*d.%0*d", len(dotParts[0]), version.Major, len(dotParts[1]), version.Minor),
-			fmt.Sprintf("%0*d.%0*d.%0*d", len(dotParts[0]), version.Major, len(dotParts[1]), version.Minor, len(dotParts[2]), version.Patch),
-		}, nil
+// isVersion returns true if the tag is a semantic or calendar
+// version.
+func isVersion(tag string) bool {
+	if _, err := ParseSemver(tag, false); err == nil {
+		return true
 	}
-	return []string{
-		fmt.Sprintf("%0*d", len(dotParts[0]), version.Major),
-		fmt.Sprintf("%0*d.%0*d", len(dotParts[0]), version.Major, len(dotParts[1]), version.Minor),
-		fmt.Sprintf("%0*d.%0*d.%0*d", len(dotParts[0]), version.Major, len(dotParts[1]), version.Minor, len(dotParts[2]), version.Patch),
-	}, nil
+	_, err := ParseCalVer(tag)
+	return err == nil
 }
 
 // UseAutoTag for keep only default branch for latest tag.
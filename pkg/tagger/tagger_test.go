@@ -4,6 +4,7 @@ package tagger
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -197,3 +198,108 @@ func TestUseAutoTag(t *testing.T) {
 		}
 	}
 }
+
+func TestSanitizeTag(t *testing.T) {
+	long := strings.Repeat("a", 130)
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "simple", in: "develop", want: "develop"},
+		{name: "slash and case", in: "feature/Foo_Bar", want: "feature-foo_bar"},
+		{name: "repeated invalid", in: "fix//issue #12", want: "fix-issue-12"},
+		{name: "leading dot and dash", in: ".-hotfix", want: "hotfix"},
+		{name: "trailing invalid", in: "release/", want: "release"},
+		{name: "invalid only", in: "///", want: ""},
+		{name: "max length", in: long[:128], want: long[:128]},
+		{name: "truncated", in: long, want: long[:119] + "-" + "1e3c4f47"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeTag(tt.in); got != tt.want {
+			t.Errorf("%q. SanitizeTag() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := SanitizeTag(tt.in); len(got) > maxTagLength {
+			t.Errorf("%q. SanitizeTag() returned %d characters", tt.name, len(got))
+		}
+	}
+}
+
+func TestBranchTagsSuffix(t *testing.T) {
+	tests := []struct {
+		ref    string
+		suffix string
+		want   []string
+		ok     bool
+	}{
+		{ref: "refs/heads/feature/Foo_Bar", want: []string{"feature-foo_bar"}, ok: true},
+		{ref: "refs/heads/develop", suffix: "linux-amd64", want: []string{"develop-linux-amd64"}, ok: true},
+		{ref: "refs/tags/v1.0.0"},
+		{ref: "refs/pull/12/head"},
+		{ref: "refs/heads/..."},
+	}
+	for _, tt := range tests {
+		got, ok := BranchTagsSuffix(tt.ref, tt.suffix)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BranchTagsSuffix(%q) = %v, %v, want %v, %v", tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPullRequestTagsSuffix(t *testing.T) {
+	tests := []struct {
+		ref    string
+		suffix string
+		want   []string
+		ok     bool
+	}{
+		{ref: "refs/pull/123/head", want: []string{"pr-123"}, ok: true},
+		{ref: "refs/pull/123/merge", suffix: "nanoserver", want: []string{"pr-123-nanoserver"}, ok: true},
+		{ref: "refs/merge-requests/7/head", want: []string{"pr-7"}, ok: true},
+		{ref: "refs/pull/abc/head"},
+		{ref: "refs/heads/pull/123/head"},
+		{ref: "refs/tags/v1.0.0"},
+	}
+	for _, tt := range tests {
+		got, ok := PullRequestTagsSuffix(tt.ref, tt.suffix)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PullRequestTagsSuffix(%q) = %v, %v, want %v, %v", tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStripComponentPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		pattern string
+		want    string
+		ok      bool
+	}{
+		{name: "no pattern", ref: "refs/tags/v1.0.0", want: "refs/tags/v1.0.0", ok: true},
+		{name: "slash separator", ref: "refs/tags/payments-api/v2.3.1", pattern: "payments-api", want: "refs/tags/v2.3.1", ok: true},
+		{name: "dash separator", ref: "refs/tags/payments-api-v2.3.1", pattern: "payments-api", want: "refs/tags/v2.3.1", ok: true},
+		{name: "dash separator without v", ref: "refs/tags/payments-api-2.3.1", pattern: "payments-api", want: "refs/tags/2.3.1", ok: true},
+		{name: "prerelease", ref: "refs/tags/payments-api-v2.3.1-rc.1", pattern: "payments-api", want: "refs/tags/v2.3.1-rc.1", ok: true},
+		{name: "glob", ref: "refs/tags/payments-api/v2.3.1", pattern: "payments-*", want: "refs/tags/v2.3.1", ok: true},
+		{name: "other component", ref: "refs/tags/billing/v1.0.0", pattern: "payments-api"},
+		{name: "component prefix of other component", ref: "refs/tags/payments-api-worker-v1.0.0", pattern: "payments-api"},
+		{name: "unprefixed tag", ref: "refs/tags/v1.0.0", pattern: "payments-api"},
+		{name: "invalid version", ref: "refs/tags/payments-api/latest", pattern: "payments-api", want: "refs/tags/latest", ok: true},
+		{name: "branch", ref: "refs/heads/main", pattern: "payments-api", want: "refs/heads/main", ok: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := StripComponentPrefix(tt.ref, tt.pattern)
+		if err != nil {
+			t.Errorf("%q. Unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q. StripComponentPrefix() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, _, err := StripComponentPrefix("refs/tags/v1.0.0", "[payments"); err == nil {
+		t.Errorf("Expect error for invalid pattern")
+	}
+}
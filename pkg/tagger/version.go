@@ -0,0 +1,188 @@
+package tagger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic or calendar version parsed from a
+// tag. The numbers are kept as written as well, so that zero
+// padded versions such as 18.06.0 or 2026.01.05 keep their
+// padding in the expanded tags.
+type Version struct {
+	Major      int    // major version, or year of a calendar version
+	Minor      int    // minor version, or month of a calendar version
+	Patch      int    // patch version, or day or micro version; -1 if absent from a calendar version
+	PreRelease string // pre-release of a semantic version, or modifier of a calendar version
+	Build      string // build metadata of a semantic version
+
+	parts []string // major, minor and patch as written
+}
+
+var identifiers = regexp.MustCompile(`^[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*$`)
+
+// ParseSemver parses a semantic version with an optional v
+// prefix. Underscores are replaced with dashes, as semantic
+// versions do not allow them. With shorthand, MAJOR and
+// MAJOR.MINOR versions are accepted and completed with zeros,
+// they cannot carry a pre-release or build metadata.
+func ParseSemver(version string, shorthand bool) (*Version, error) {
+	s := strings.ReplaceAll(strings.TrimPrefix(version, "v"), "_", "-")
+	v := &Version{}
+	if i := strings.Index(s, "+"); i >= 0 {
+		s, v.Build = s[:i], s[i+1:]
+		if !identifiers.MatchString(v.Build) {
+			return nil, fmt.Errorf("%q is not a semantic version: invalid build metadata", version)
+		}
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, v.PreRelease = s[:i], s[i+1:]
+		if !identifiers.MatchString(v.PreRelease) {
+			return nil, fmt.Errorf("%q is not a semantic version: invalid pre-release", version)
+		}
+	}
+
+	v.parts = strings.Split(s, ".")
+	switch {
+	case len(v.parts) == 3:
+	case len(v.parts) < 3 && shorthand && v.PreRelease == "" && v.Build == "":
+		for len(v.parts) < 3 {
+			v.parts = append(v.parts, "0")
+		}
+	default:
+		return nil, fmt.Errorf("%q is not a semantic version", version)
+	}
+	nums, err := parseNumbers(v.parts)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a semantic version", version)
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// ParseCalVer parses a calendar version such as 2026.10,
+// 2026.10.3 or 2026.01.05 with an optional v prefix and an
+// optional modifier, e.g. 2026.10.3-rc1. The year has 2 or 4
+// digits, the second part is a month, and the optional third
+// part is either a day or a micro version.
+func ParseCalVer(version string) (*Version, error) {
+	s := strings.TrimPrefix(version, "v")
+	v := &Version{Patch: -1}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, v.PreRelease = s[:i], s[i+1:]
+		if !identifiers.MatchString(v.PreRelease) {
+			return nil, fmt.Errorf("%q is not a calendar version: invalid modifier", version)
+		}
+	}
+	v.parts = strings.Split(s, ".")
+	if len(v.parts) != 2 && len(v.parts) != 3 {
+		return nil, fmt.Errorf("%q is not a calendar version", version)
+	}
+	nums, err := parseNumbers(v.parts)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a calendar version", version)
+	}
+	v.Major, v.Minor = nums[0], nums[1]
+	if len(nums) == 3 {
+		v.Patch = nums[2]
+	}
+	if n := len(v.parts[0]); n != 2 && n != 4 {
+		return nil, fmt.Errorf("%q is not a calendar version: invalid year", version)
+	}
+	if v.Minor < 1 || v.Minor > 12 || len(v.parts[1]) > 2 {
+		return nil, fmt.Errorf("%q is not a calendar version: invalid month", version)
+	}
+	return v, nil
+}
+
+func parseNumbers(parts []string) ([]int, error) {
+	var nums []int
+	for _, part := range parts {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return nil, fmt.Errorf("invalid number %q", part)
+			}
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// String returns the version as written, without v prefix.
+func (v *Version) String() string {
+	s := strings.Join(v.parts, ".")
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// tag returns the version as a tag: build metadata follows an
+// underscore, as tags do not allow a plus sign.
+func (v *Version) tag() string {
+	return strings.Replace(v.String(), "+", "_", 1)
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal
+// or higher than o, following the semver precedence rules:
+// numbers are compared numerically, a pre-release is lower
+// than its release, and build metadata is ignored.
+func (v *Version) Compare(o *Version) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			return compareInts(d[0], d[1])
+		}
+	}
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case o.PreRelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.PreRelease, "."), strings.Split(o.PreRelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifiers(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+// compareIdentifiers compares numeric identifiers numerically
+// and other identifiers lexically, numeric identifiers are
+// lower than the others.
+func compareIdentifiers(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package tagger
+
+import (
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version   string
+		shorthand bool
+		want      string
+		wantErr   bool
+	}{
+		{version: "1.2.3", want: "1.2.3"},
+		{version: "v1.2.3", want: "1.2.3"},
+		{version: "18.06.0", want: "18.06.0"},
+		{version: "1.2.3-rc.1", want: "1.2.3-rc.1"},
+		{version: "1.2.3+linux_amd64", want: "1.2.3+linux-amd64"},
+		{version: "1.2.3-rc1+bld", want: "1.2.3-rc1+bld"},
+		{version: "v1.2", shorthand: true, want: "1.2.0"},
+		{version: "v1", shorthand: true, want: "1.0.0"},
+		{version: "v1.2", wantErr: true},
+		{version: "20190203", wantErr: true},
+		{version: "v1+bld", shorthand: true, wantErr: true},
+		{version: "x1.0.0", wantErr: true},
+		{version: "1.2.3-", wantErr: true},
+		{version: "1.2.3+", wantErr: true},
+		{version: "1.2.3-rc..1", wantErr: true},
+		{version: "1.2.3.4", wantErr: true},
+		{version: "latest", shorthand: true, wantErr: true},
+		{version: "", shorthand: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		v, err := ParseSemver(tt.version, tt.shorthand)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expect error for %q, got %s", tt.version, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.version, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("ParseSemver(%q) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseCalVer(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: "2026.10.3", want: "2026.10.3"},
+		{version: "v2026.10.3", want: "2026.10.3"},
+		{version: "2026.01.05", want: "2026.01.05"},
+		{version: "2026.10", want: "2026.10"},
+		{version: "26.1.0", want: "26.1.0"},
+		{version: "2026.10.3-rc1", want: "2026.10.3-rc1"},
+		{version: "1.2.3", wantErr: true},
+		{version: "2026.13.1", wantErr: true},
+		{version: "2026.0.1", wantErr: true},
+		{version: "2026", wantErr: true},
+		{version: "20260203", wantErr: true},
+		{version: "2026.10.3.1", wantErr: true},
+		{version: "2026.10.x", wantErr: true},
+		{version: "2026.10.3-", wantErr: true},
+	}
+	for _, tt := range tests {
+		v, err := ParseCalVer(tt.version)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expect error for %q, got %s", tt.version, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.version, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("ParseCalVer(%q) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "1.2.3", b: "1.2.4", want: -1},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "18.06.0", b: "18.6.0", want: 0},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0-beta.11", want: 1},
+		{a: "1.0.0+a", b: "1.0.0+b", want: 0},
+	}
+	for _, tt := range tests {
+		a, err := ParseSemver(tt.a, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseSemver(tt.b, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	a, _ := ParseCalVer("2026.10")
+	b, _ := ParseCalVer("2026.10.0")
+	if got := a.Compare(b); got != -1 {
+		t.Errorf("Compare(2026.10, 2026.10.0) = %d, want -1", got)
+	}
+}
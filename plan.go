@@ -0,0 +1,127 @@
+package kaniko
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// redacted replaces secret values in output.
+const redacted = "******"
+
+type (
+	// Plan defines the fully resolved build plan reported in dry-run mode.
+	Plan struct {
+		Mode         string          `json:"mode"`
+		Source       string          `json:"source,omitempty"`
+		Tags         []string        `json:"tags"`
+		Destinations []string        `json:"destinations"`
+		CacheRepo    string          `json:"cacheRepo,omitempty"`
+		Registries   []string        `json:"registries"`
+		RemoteTags   []string        `json:"remoteTags,omitempty"`
+		Executions   []PlanExecution `json:"executions,omitempty"`
+	}
+
+	// PlanExecution defines a single executor invocation of the plan.
+	PlanExecution struct {
+		Platform string   `json:"platform,omitempty"`
+		Path     string   `json:"path"`
+		Args     []string `json:"args"`
+	}
+)
+
+// plan returns the resolved build plan for the given tags, which are expanded
+// the same way in every mode. Build arg values are redacted as they commonly
+// carry secrets.
+func (p Plugin) plan(tags []string) (Plan, error) {
+	plan := Plan{
+		Mode:         "build",
+		Tags:         p.Build.expandTags(tags),
+		Destinations: []string{},
+		Registries:   p.Registries,
+	}
+	if plan.Tags == nil {
+		plan.Tags = []string{}
+	}
+	if plan.Registries == nil {
+		plan.Registries = []string{}
+	}
+	if p.Build.EnableCache {
+		plan.CacheRepo = p.Build.CacheRepo
+	}
+
+	if p.Build.PromoteFrom != "" || p.Build.PushOnly {
+		plan.Mode = "push-only"
+		plan.Source = p.Build.SourceTarPath
+		if p.Build.PromoteFrom != "" {
+			plan.Mode = "promote"
+			plan.Source = p.Build.PromoteFrom
+		}
+		for _, label := range plan.Tags {
+			plan.Destinations = append(plan.Destinations, fmt.Sprintf("%s:%s", p.Build.Repo, label))
+		}
+		return plan, nil
+	}
+
+	if !p.Build.NoPush || p.Build.TarPath != "" {
+		for _, label := range plan.Tags {
+			plan.Destinations = append(plan.Destinations, fmt.Sprintf("%s:%s", p.Build.Repo, label))
+		}
+	}
+
+	m := p.masker()
+	if len(p.Build.Platforms) == 0 {
+		executor, executorTags, remoteLabels := p.splitRemoteTags(tags)
+		plan.RemoteTags = remoteLabels
+		plan.Executions = []PlanExecution{{
+			Path: defaultExecutorPath,
+			Args: m.maskArgs(redactBuildArgs(executor.executorArgs(executorTags))),
+		}}
+		return plan, nil
+	}
+
+	targets, err := p.platformTargets(tags)
+	if err != nil {
+		return plan, err
+	}
+	for _, target := range targets {
+		plan.Executions = append(plan.Executions, PlanExecution{
+			Platform: target.platform.String(),
+			Path:     defaultExecutorPath,
+			Args:     m.maskArgs(redactBuildArgs(Plugin{Build: target.build}.executorArgs([]string{target.tag}))),
+		})
+	}
+	return plan, nil
+}
+
+// printPlan writes the resolved build plan to stdout as JSON.
+func (p Plugin) printPlan(tags []string) error {
+	plan, err := p.plan(tags)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build plan: %v", err)
+	}
+	fmt.Fprintln(os.Stdout, string(b))
+	return nil
+}
+
+// redactBuildArgs returns a copy of the executor arguments with the value of
+// every build arg replaced.
+func redactBuildArgs(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i := 0; i < len(out)-1; i++ {
+		if out[i] != "--build-arg" {
+			continue
+		}
+		i++
+		if key, _, ok := strings.Cut(out[i], "="); ok {
+			out[i] = key + "=" + redacted
+		}
+	}
+	return out
+}
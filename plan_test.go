@@ -0,0 +1,239 @@
+package kaniko
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		build Build
+		tags  []string
+		want  Plan
+	}{
+		{
+			name: "build",
+			build: Build{
+				Dockerfile:  "Dockerfile",
+				Context:     ".",
+				Repo:        "foo/bar",
+				Args:        []string{"TOKEN=secret", "EMPTY="},
+				ExpandTag:   true,
+				EnableCache: true,
+				CacheRepo:   "foo/cache",
+			},
+			tags: []string{"v1.2.3"},
+			want: Plan{
+				Mode:         "build",
+				Tags:         []string{"1", "1.2", "1.2.3"},
+				Destinations: []string{"foo/bar:1", "foo/bar:1.2", "foo/bar:1.2.3"},
+				CacheRepo:    "foo/cache",
+				Registries:   []string{"https://index.docker.io/v1/"},
+				Executions: []PlanExecution{{
+					Path: defaultExecutorPath,
+					Args: []string{
+						"--dockerfile=Dockerfile",
+						"--context=dir://.",
+						"--destination=foo/bar:1",
+						"--destination=foo/bar:1.2",
+						"--destination=foo/bar:1.2.3",
+						"--build-arg", "TOKEN=" + redacted,
+						"--build-arg", "EMPTY=" + redacted,
+						"--cache=true",
+						"--cache-repo=foo/cache",
+					},
+				}},
+			},
+		},
+		{
+			name: "no_push",
+			build: Build{
+				Dockerfile: "Dockerfile",
+				Context:    ".",
+				Repo:       "foo/bar",
+				NoPush:     true,
+			},
+			tags: []string{"latest"},
+			want: Plan{
+				Mode:         "build",
+				Tags:         []string{"latest"},
+				Destinations: []string{},
+				Registries:   []string{"https://index.docker.io/v1/"},
+				Executions: []PlanExecution{{
+					Path: defaultExecutorPath,
+					Args: []string{
+						"--dockerfile=Dockerfile",
+						"--context=dir://.",
+						"--no-push",
+					},
+				}},
+			},
+		},
+		{
+			name: "platforms",
+			build: Build{
+				Dockerfile: "Dockerfile",
+				Context:    ".",
+				Repo:       "foo/bar",
+				Platforms:  []string{"linux/amd64", "linux/arm64"},
+			},
+			tags: []string{"latest"},
+			want: Plan{
+				Mode:         "build",
+				Tags:         []string{"latest"},
+				Destinations: []string{"foo/bar:latest"},
+				Registries:   []string{"https://index.docker.io/v1/"},
+				Executions: []PlanExecution{
+					{
+						Platform: "linux/amd64",
+						Path:     defaultExecutorPath,
+						Args: []string{
+							"--dockerfile=Dockerfile",
+							"--context=dir://.",
+							"--destination=foo/bar:latest-linux-amd64",
+							"--custom-platform=linux/amd64",
+						},
+					},
+					{
+						Platform: "linux/arm64",
+						Path:     defaultExecutorPath,
+						Args: []string{
+							"--dockerfile=Dockerfile",
+							"--context=dir://.",
+							"--destination=foo/bar:latest-linux-arm64",
+							"--custom-platform=linux/arm64",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "push_only",
+			build: Build{
+				Repo:          "foo/bar",
+				PushOnly:      true,
+				SourceTarPath: "image.tar",
+			},
+			tags: []string{"latest", "v1"},
+			want: Plan{
+				Mode:         "push-only",
+				Source:       "image.tar",
+				Tags:         []string{"latest", "v1"},
+				Destinations: []string{"foo/bar:latest", "foo/bar:v1"},
+				Registries:   []string{"https://index.docker.io/v1/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Plugin{
+				Build:      tt.build,
+				Registries: []string{"https://index.docker.io/v1/"},
+			}
+			got, err := p.plan(tt.tags)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected plan (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecDryRun(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{
+		Build: Build{
+			Dockerfile: dockerfile,
+			Context:    dir,
+			Repo:       "foo/bar",
+			Tags:       []string{"latest"},
+			DigestFile: filepath.Join(dir, "digest"),
+			DryRun:     true,
+		},
+		Runner: RunnerFunc(func(Execution) (Result, error) {
+			t.Fatal("executor must not run")
+			return Result{}, nil
+		}),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(p.Build.DigestFile); !os.IsNotExist(err) {
+		t.Errorf("digest file must not be written in dry-run mode")
+	}
+}
+
+func TestExecDryRunBuildPlan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s in dry-run mode", r.URL)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	p := Plugin{
+		Build: Build{
+			DockerfileURL: server.URL + "/Dockerfile",
+			Context:       dir,
+			Repo:          "foo/bar",
+			Tags:          []string{"v1.2.3"},
+			ExpandTag:     true,
+			DryRun:        true,
+		},
+		Runner: RunnerFunc(func(Execution) (Result, error) {
+			t.Fatal("executor must not run")
+			return Result{}, nil
+		}),
+	}
+	out := captureStdout(t, func() {
+		if err := p.Exec(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	var plan Plan
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("Unexpected plan output %q: %v", out, err)
+	}
+	if diff := cmp.Diff([]string{"1", "1.2", "1.2.3"}, plan.Tags); diff != "" {
+		t.Errorf("Unexpected plan tags (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"foo/bar:1", "foo/bar:1.2", "foo/bar:1.2.3"}, plan.Destinations); diff != "" {
+		t.Errorf("Unexpected plan destinations (-want +got):\n%s", diff)
+	}
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
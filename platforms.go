@@ -0,0 +1,259 @@
+package kaniko
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/drone/drone-kaniko/pkg/artifact"
+)
+
+// platformTarget defines the executor build of a single platform of a
+// multi-platform build.
+type platformTarget struct {
+	platform *v1.Platform
+	build    Build  // build configuration for the platform
+	tag      string // per-platform tag the image is pushed to
+}
+
+// platformBuild holds the outcome of building a single platform of a
+// multi-platform build.
+type platformBuild struct {
+	platform *v1.Platform
+	tag      string // per-platform tag the image was pushed to
+	digest   string // digest reported by the executor
+}
+
+// platformSuffix returns a tag-safe suffix for the platform, e.g.
+// "linux/arm64/v8" becomes "linux-arm64-v8".
+func platformSuffix(platform string) string {
+	return strings.ReplaceAll(strings.TrimSpace(platform), "/", "-")
+}
+
+// withSuffix inserts the suffix in front of the file extension of path, e.g.
+// "image.tar" becomes "image-linux-amd64.tar".
+func withSuffix(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), suffix, ext)
+}
+
+// platformTargets returns the executor build of each platform.
+//
+// Each platform is pushed to the most specific label of the first tag with
+// the platform appended, e.g. "1.2.3-linux-amd64", or saved to the tarball
+// path with the platform appended when tar-path is set.
+func (p Plugin) platformTargets(tags []string) ([]platformTarget, error) {
+	if p.Build.CustomPlatform != "" {
+		return nil, fmt.Errorf("platform and platforms cannot be used together. please define only one")
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required for a multi-platform build")
+	}
+
+	baseTag := p.Build.labelsForTag(tags[0])
+	platformTagBase := baseTag[len(baseTag)-1]
+
+	var targets []platformTarget
+	for _, platform := range p.Build.Platforms {
+		parsed, err := v1.ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform %q: %v", platform, err)
+		}
+		suffix := platformSuffix(platform)
+
+		build := p.Build
+		build.CustomPlatform = platform
+		build.ExpandTag = false
+		if build.DigestFile != "" {
+			build.DigestFile = withSuffix(build.DigestFile, suffix)
+		}
+		if build.TarPath != "" {
+			build.TarPath = withSuffix(build.TarPath, suffix)
+		}
+		targets = append(targets, platformTarget{
+			platform: parsed,
+			build:    build,
+			tag:      fmt.Sprintf("%s-%s", platformTagBase, suffix),
+		})
+	}
+	return targets, nil
+}
+
+// execPlatforms runs one executor build per platform and, unless no-push is
+// set, publishes an OCI image index that references every per-platform image
+// under each of the requested tags, and under the content key tag when set.
+func (p Plugin) execPlatforms(runner Runner, tags []string, contentTag string) error {
+	targets, err := p.platformTargets(tags)
+	if err != nil {
+		return err
+	}
+
+	labels := p.Build.expandTags(tags)
+
+	// protected platform tags that already exist are not pushed, they are
+	// checked against the digest of the platform build once it is known
+	var existing map[string]string
+	if len(p.Build.ImmutableTags) > 0 && !p.Build.NoPush {
+		var platformTags []string
+		for _, target := range targets {
+			platformTags = append(platformTags, target.tag)
+		}
+		if existing, err = p.existingImmutableTags(platformTags, p.Build.nameOptions(), p.remoteOptions()); err != nil {
+			return err
+		}
+	}
+
+	digestDir, err := os.MkdirTemp("", "kaniko-platforms")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for platform digests: %v", err)
+	}
+	defer os.RemoveAll(digestDir)
+
+	var builds []platformBuild
+	for _, target := range targets {
+		build := target.build
+		if build.DigestFile == "" {
+			build.DigestFile = filepath.Join(digestDir, platformSuffix(build.CustomPlatform))
+		}
+		existingDigest, exists := existing[target.tag]
+		if exists {
+			// the executor writes the digest file without pushing
+			build.NoPush = true
+		}
+
+		execution := Execution{
+			Path:   defaultExecutorPath,
+			Args:   Plugin{Build: build}.executorArgs([]string{target.tag}),
+			Env:    os.Environ(),
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		}
+		p.trace(execution)
+
+		if _, err := runner.Run(execution); err != nil {
+			return fmt.Errorf("failed to build platform %s: %v", target.platform, err)
+		}
+		digest := strings.TrimSpace(getDigest(build.DigestFile))
+		if exists {
+			if err := p.immutableConflicts(map[string]string{target.tag: existingDigest}, digest); err != nil {
+				return err
+			}
+		}
+		builds = append(builds, platformBuild{
+			platform: target.platform,
+			tag:      target.tag,
+			digest:   digest,
+		})
+	}
+
+	if p.Build.NoPush {
+		fmt.Println("Skipping image index, no-push is set")
+		return nil
+	}
+
+	idx, err := p.platformIndex(builds)
+	if err != nil {
+		return err
+	}
+	digest, err := idx.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to compute image index digest: %v", err)
+	}
+
+	if err := p.checkImmutableTags(digest.String(), labels, p.Build.nameOptions(), p.remoteOptions()); err != nil {
+		return err
+	}
+
+	for _, label := range labels {
+		ref, err := name.ParseReference(fmt.Sprintf("%s:%s", p.Build.Repo, label), p.Build.nameOptions()...)
+		if err != nil {
+			return fmt.Errorf("invalid destination %s:%s: %v", p.Build.Repo, label, err)
+		}
+		if err := remote.WriteIndex(ref, idx, p.remoteOptions()...); err != nil {
+			return fmt.Errorf("failed to push image index to %s: %v", ref, err)
+		}
+		fmt.Printf("Successfully pushed image index %s to %s\n", digest, ref)
+	}
+
+	if contentTag != "" {
+		if err := p.tagRemotely(digest.String(), []string{contentTag}); err != nil {
+			return err
+		}
+	}
+
+	if p.Build.VerifyPush {
+		if err := p.verifyTags(digest.String(), labels); err != nil {
+			return err
+		}
+		for _, b := range builds {
+			if err := p.verifyTags(b.digest, []string{b.tag}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Build.DigestFile != "" {
+		if err := os.WriteFile(p.Build.DigestFile, []byte(digest.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write digest file at path: %s: %v", p.Build.DigestFile, err)
+		}
+	}
+
+	if p.Artifact.ArtifactFile != "" {
+		var images []artifact.Image
+		for _, tag := range p.Artifact.Tags {
+			images = append(images, artifact.Image{
+				Image:  fmt.Sprintf("%s:%s", p.Artifact.Repo, tag),
+				Digest: digest.String(),
+			})
+		}
+		for _, b := range builds {
+			images = append(images, artifact.Image{
+				Image:    fmt.Sprintf("%s:%s", p.Artifact.Repo, b.tag),
+				Digest:   b.digest,
+				Platform: b.platform.String(),
+			})
+		}
+		err := artifact.WritePluginArtifactFileImages(p.Artifact.RegistryType, p.Artifact.ArtifactFile, p.Artifact.Registry, images)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write plugin artifact file at path: %s with error: %s\n", p.Artifact.ArtifactFile, err)
+		}
+	}
+	return nil
+}
+
+// platformIndex assembles an OCI image index from the pushed per-platform images.
+func (p Plugin) platformIndex(builds []platformBuild) (v1.ImageIndex, error) {
+	b := p.Build
+	idx := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+	for _, pb := range builds {
+		if pb.digest == "" {
+			return nil, fmt.Errorf("no digest was reported for platform %s", pb.platform)
+		}
+		ref, err := name.ParseReference(fmt.Sprintf("%s@%s", b.Repo, pb.digest), b.nameOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference for platform %s: %v", pb.platform, err)
+		}
+		img, err := remote.Image(ref, p.remoteOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch image for platform %s: %v", pb.platform, err)
+		}
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: pb.platform,
+			},
+		})
+	}
+	if b.OCILabels {
+		idx = mutate.Annotations(idx, ociAnnotations(b.Labels)).(v1.ImageIndex)
+	}
+	return idx, nil
+}
@@ -0,0 +1,194 @@
+package kaniko
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/drone/drone-kaniko/pkg/artifact"
+)
+
+// fakeExecutor returns a Runner that pushes a random image carrying every
+// --label to every --destination and records its digest in --digest-file,
+// mimicking kaniko.
+func fakeExecutor(t *testing.T) Runner {
+	return RunnerFunc(func(e Execution) (Result, error) {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var digestFile string
+		var dests []string
+		labels := map[string]string{}
+		for _, arg := range e.Args {
+			switch {
+			case strings.HasPrefix(arg, "--destination="):
+				dests = append(dests, strings.TrimPrefix(arg, "--destination="))
+			case strings.HasPrefix(arg, "--digest-file="):
+				digestFile = strings.TrimPrefix(arg, "--digest-file=")
+			case strings.HasPrefix(arg, "--label="):
+				key, value, _ := strings.Cut(strings.TrimPrefix(arg, "--label="), "=")
+				labels[key] = value
+			}
+		}
+		if img, err = mutate.Config(img, v1.Config{Labels: labels}); err != nil {
+			t.Fatal(err)
+		}
+		for _, dest := range dests {
+			ref, err := name.ParseReference(dest)
+			if err != nil {
+				return Result{ExitCode: 1}, err
+			}
+			if err := remote.Write(ref, img); err != nil {
+				return Result{ExitCode: 1}, err
+			}
+		}
+		if digestFile != "" {
+			digest, _ := img.Digest()
+			if err := os.WriteFile(digestFile, []byte(digest.String()), 0644); err != nil {
+				return Result{ExitCode: 1}, err
+			}
+		}
+		return Result{}, nil
+	})
+}
+
+// newTestRegistry starts an in-memory registry and returns its host.
+func newTestRegistry(t *testing.T) string {
+	s := httptest.NewServer(registry.New())
+	t.Cleanup(s.Close)
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func TestPlatformSuffix(t *testing.T) {
+	tests := map[string]string{
+		"linux/amd64":    "linux-amd64",
+		"linux/arm64/v8": "linux-arm64-v8",
+		" linux/arm/v7 ": "linux-arm-v7",
+	}
+	for platform, want := range tests {
+		if got := platformSuffix(platform); got != want {
+			t.Errorf("platformSuffix(%q) = %q, want %q", platform, got, want)
+		}
+	}
+	if got, want := withSuffix("/out/image.tar", "linux-amd64"), "/out/image-linux-amd64.tar"; got != want {
+		t.Errorf("withSuffix() = %q, want %q", got, want)
+	}
+}
+
+func TestExecPlatforms(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifactFile := filepath.Join(dir, "artifact.json")
+
+	p := Plugin{
+		Build: Build{
+			Dockerfile: dockerfile,
+			Context:    dir,
+			Repo:       host + "/foo/bar",
+			Tags:       []string{"v1.2.3"},
+			ExpandTag:  true,
+			Platforms:  []string{"linux/amd64", "linux/arm64"},
+			DigestFile: filepath.Join(dir, "digest"),
+		},
+		Artifact: Artifact{
+			Tags:         []string{"v1.2.3"},
+			Repo:         host + "/foo/bar",
+			ArtifactFile: artifactFile,
+			RegistryType: artifact.Docker,
+		},
+		Runner: fakeExecutor(t),
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	digest := getDigest(p.Build.DigestFile)
+	for _, tag := range []string{"1", "1.2", "1.2.3"} {
+		ref, _ := name.ParseReference(fmt.Sprintf("%s/foo/bar:%s", host, tag))
+		idx, err := remote.Index(ref)
+		if err != nil {
+			t.Fatalf("failed to fetch index for tag %s: %v", tag, err)
+		}
+		got, _ := idx.Digest()
+		if got.String() != digest {
+			t.Errorf("index digest for tag %s = %s, want %s", tag, got, digest)
+		}
+		manifest, _ := idx.IndexManifest()
+		var platforms []string
+		for _, m := range manifest.Manifests {
+			platforms = append(platforms, m.Platform.String())
+		}
+		if want := []string{"linux/amd64", "linux/arm64"}; !cmp.Equal(platforms, want) {
+			t.Errorf("index platforms = %q, want %q", platforms, want)
+		}
+	}
+
+	for _, tag := range []string{"1.2.3-linux-amd64", "1.2.3-linux-arm64"} {
+		ref, _ := name.ParseReference(fmt.Sprintf("%s/foo/bar:%s", host, tag))
+		if _, err := remote.Image(ref); err != nil {
+			t.Errorf("missing per-platform image %s: %v", tag, err)
+		}
+	}
+
+	b, err := os.ReadFile(artifactFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got artifact.DockerArtifact
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Data.Images) != 3 {
+		t.Fatalf("artifact images = %d, want 3", len(got.Data.Images))
+	}
+	if got.Data.Images[0].Digest != digest || got.Data.Images[0].Platform != "" {
+		t.Errorf("artifact index image = %+v, want digest %s", got.Data.Images[0], digest)
+	}
+	for i, platform := range []string{"linux/amd64", "linux/arm64"} {
+		img := got.Data.Images[i+1]
+		if img.Platform != platform || img.Digest == "" || img.Digest == digest {
+			t.Errorf("artifact platform image = %+v, want platform %s", img, platform)
+		}
+	}
+}
+
+func TestExecPlatformsConflict(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{
+		Build: Build{
+			Dockerfile:     dockerfile,
+			Repo:           "foo/bar",
+			Tags:           []string{"latest"},
+			CustomPlatform: "linux/amd64",
+			Platforms:      []string{"linux/amd64", "linux/arm64"},
+		},
+		Runner: RunnerFunc(func(Execution) (Result, error) {
+			t.Fatal("executor must not run")
+			return Result{}, nil
+		}),
+	}
+	if err := p.Exec(); err == nil {
+		t.Errorf("Expected an error, but got none")
+	}
+}
@@ -0,0 +1,145 @@
+package kaniko
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// preflightCheck is the result of checking the access to a repository.
+type preflightCheck struct {
+	registry   string
+	repository string // repository, with the tag or digest for pull checks
+	scope      string // push or pull
+	err        error
+}
+
+// preflight checks the credentials of the destination and cache repositories
+// for push access and of the base images of the Dockerfile for pull access
+// before the build starts, and prints the results as a table. Unless
+// PreflightWarnOnly is set, it fails when a check failed.
+func (p Plugin) preflight(out io.Writer) error {
+	var checks []preflightCheck
+	push := func(repo string) {
+		check := preflightCheck{registry: "-", repository: repo, scope: "push"}
+		r, err := name.NewRepository(repo, p.Build.nameOptions()...)
+		if err == nil {
+			check.registry, check.repository = r.RegistryStr(), r.RepositoryStr()
+			// pinging /v2/ and exchanging a push scoped token is not enough
+			// for every registry, an upload is initiated and cancelled too
+			err = remote.CheckPushPermission(r.Tag("latest"), p.keychain(), p.transport())
+		}
+		check.err = err
+		checks = append(checks, check)
+	}
+	if !p.Build.NoPush {
+		push(p.Build.Repo)
+		if p.Build.EnableCache && p.Build.CacheRepo != "" {
+			push(p.Build.CacheRepo)
+		}
+	}
+
+	images, err := baseImages(p.Build.Dockerfile)
+	if err != nil && !p.Build.remoteContext() {
+		return err
+	}
+	for _, image := range images {
+		check := preflightCheck{registry: "-", repository: image, scope: "pull"}
+		ref, err := name.ParseReference(image, p.Build.nameOptions()...)
+		if err == nil {
+			sep := ":"
+			if _, ok := ref.(name.Digest); ok {
+				sep = "@"
+			}
+			check.registry, check.repository = ref.Context().RegistryStr(), ref.Context().RepositoryStr()+sep+ref.Identifier()
+			_, err = remote.Head(ref, p.remoteOptions()...)
+		}
+		check.err = err
+		checks = append(checks, check)
+	}
+
+	failed := printPreflight(out, checks)
+	if failed == 0 {
+		return nil
+	}
+	err = fmt.Errorf("pre-flight registry check failed for %d of %d repositories", failed, len(checks))
+	if p.Build.PreflightWarnOnly {
+		fmt.Fprintf(os.Stderr, "warning: %v, continuing the build\n", err)
+		return nil
+	}
+	return err
+}
+
+// printPreflight prints the checks as a table and returns the number of
+// failed checks.
+func printPreflight(out io.Writer, checks []preflightCheck) int {
+	failed := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REGISTRY\tREPOSITORY\tSCOPE\tRESULT")
+	for _, check := range checks {
+		result := "ok"
+		if check.err != nil {
+			failed++
+			result = "failed: " + strings.ReplaceAll(check.err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.registry, check.repository, check.scope, result)
+	}
+	w.Flush()
+	return failed
+}
+
+// baseImages returns the images of the FROM instructions of the Dockerfile.
+// Build stages, scratch and images that depend on build args are skipped.
+func baseImages(dockerfile string) ([]string, error) {
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dockerfile: %v", err)
+	}
+	defer f.Close()
+
+	var images []string
+	seen := map[string]bool{"scratch": true}
+	scanner := bufio.NewScanner(f)
+	var line string
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+		fields := strings.Fields(line)
+		line = ""
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+		image := args[0]
+		if !seen[strings.ToLower(image)] && !strings.Contains(image, "$") {
+			seen[strings.ToLower(image)] = true
+			images = append(images, image)
+		}
+		if len(args) == 3 && strings.EqualFold(args[1], "AS") {
+			seen[strings.ToLower(args[2])] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dockerfile: %v", err)
+	}
+	return images, nil
+}
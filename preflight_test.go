@@ -0,0 +1,115 @@
+package kaniko
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestBaseImages(t *testing.T) {
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	content := `# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.25
+FROM golang:${GO_VERSION} AS build
+FROM --platform=$BUILDPLATFORM \
+    alpine:3.20 AS certs
+from build as test
+FROM gcr.io/distroless/static:nonroot
+FROM alpine:3.20
+FROM scratch
+`
+	if err := os.WriteFile(dockerfile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := baseImages(dockerfile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{"alpine:3.20", "gcr.io/distroless/static:nonroot"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("baseImages() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	// deny uploads to the foo/denied repository
+	reg := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v2/foo/denied/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	host := strings.TrimPrefix(s.URL, "http://")
+	pushTags(t, host+"/library/base", "1.0")
+
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM "+host+"/library/base:1.0\nFROM "+host+"/library/missing:1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		build   Build
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "destinations, cache and base images",
+			build: Build{Repo: host + "/foo/bar", EnableCache: true, CacheRepo: host + "/foo/cache"},
+			want: []string{
+				"REGISTRY REPOSITORY SCOPE RESULT",
+				host + " foo/bar push ok",
+				host + " foo/cache push ok",
+				host + " library/base:1.0 pull ok",
+				host + " library/missing:1.0 pull failed",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "push denied",
+			build:   Build{Repo: host + "/foo/denied"},
+			want:    []string{host + " foo/denied push failed"},
+			wantErr: true,
+		},
+		{
+			name:  "warn only",
+			build: Build{Repo: host + "/foo/denied", PreflightWarnOnly: true},
+			want:  []string{host + " foo/denied push failed"},
+		},
+		{
+			name:  "no push",
+			build: Build{NoPush: true, Repo: host + "/foo/denied", PreflightWarnOnly: true},
+			want:  []string{host + " library/base:1.0 pull ok"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.build.Dockerfile = dockerfile
+			var out bytes.Buffer
+			err := Plugin{Build: tt.build}.preflight(&out)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("preflight() error = %v, want error %v", err, tt.wantErr)
+			}
+			got := strings.Join(strings.Fields(out.String()), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("preflight() output %q does not contain %q", got, want)
+				}
+			}
+			if tt.build.NoPush && strings.Contains(got, "push") {
+				t.Errorf("preflight() checked push access without pushing: %q", got)
+			}
+		})
+	}
+}
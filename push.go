@@ -0,0 +1,360 @@
+package kaniko
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/drone/drone-kaniko/pkg/artifact"
+	"github.com/drone/drone-kaniko/pkg/output"
+	"github.com/drone/drone-kaniko/pkg/tagger"
+)
+
+// ResolveTags returns every tag the image is published to. Auto tags and
+// expanded semver tags are resolved the same way for builds and push-only.
+func (b Build) ResolveTags() ([]string, error) {
+	tags, err := b.tags()
+	if err != nil {
+		return nil, err
+	}
+	return b.expandTags(tags), nil
+}
+
+// expandTags returns the labels of every tag.
+func (b Build) expandTags(tags []string) []string {
+	var labels []string
+	for _, tag := range tags {
+		labels = append(labels, b.labelsForTag(tag)...)
+	}
+	return labels
+}
+
+// tags returns the tags of the build, auto detected when auto-tag is set.
+// Semver tags are not expanded yet.
+func (b Build) tags() ([]string, error) {
+	if b.AutoTag && b.ExpandTag {
+		return nil, fmt.Errorf("The auto-tag flag conflicts with the expand-tag flag")
+	}
+	if err := tagger.ValidScheme(b.AutoTagScheme); err != nil {
+		return nil, err
+	}
+	if b.AutoTag {
+		return b.AutoTags()
+	}
+	if b.FloatingTags && b.ExpandTag && !b.moveLatest(b.Tags) {
+		var tags []string
+		for _, tag := range b.Tags {
+			if tag != "latest" {
+				tags = append(tags, tag)
+			}
+		}
+		return tags, nil
+	}
+	return b.Tags, nil
+}
+
+// refNameAnnotation is the OCI layout annotation holding the tag of a manifest.
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// pushSource is the image or image index pushed in push-only and promote mode.
+type pushSource struct {
+	from  string // tarball, OCI layout or image reference the source was loaded from
+	image v1.Image
+	index v1.ImageIndex
+}
+
+// digest returns the digest of the image or image index.
+func (s pushSource) digest() (v1.Hash, error) {
+	if s.index != nil {
+		return s.index.Digest()
+	}
+	return s.image.Digest()
+}
+
+// Push pushes the image found at SourceTarPath to every resolved tag of the
+// build and reports the tags in the artifact and output files.
+//
+// SourceTarPath is either a docker tarball or an OCI image layout directory.
+// Image indexes found in an OCI layout are pushed intact. The registries are
+// called with the Keychain of the plugin, like every other registry call.
+func (p Plugin) Push() error {
+	p, err := p.withTemplates()
+	if err != nil {
+		return err
+	}
+
+	if p.Build, err = p.Build.withExistingTags(p.Build.nameOptions(), p.remoteOptions()); err != nil {
+		return err
+	}
+	return p.push()
+}
+
+// push pushes the image found at SourceTarPath like Push does, for a plugin
+// whose templates are rendered and existing tags are listed already.
+func (p Plugin) push() error {
+	tags, err := p.Build.ResolveTags()
+	if errors.Is(err, errSkipBuild) {
+		fmt.Println(err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	src, err := p.loadSource()
+	if err != nil {
+		return err
+	}
+	if p.Build.OCILabels {
+		src = src.withAnnotations(ociAnnotations(p.Build.withOCILabels(NewTemplateData(os.Environ()), tags)))
+	}
+
+	return p.publish(src, tags, p.Build.nameOptions(), p.remoteOptions())
+}
+
+// publish pushes the source to every tag of the destination repository, then
+// writes the digest, artifact and output files.
+func (p Plugin) publish(src pushSource, tags []string, nameOpts []name.Option, remoteOpts []remote.Option) error {
+	if len(tags) == 0 {
+		tags = []string{"latest"}
+	}
+
+	digest, err := src.digest()
+	if err != nil {
+		return fmt.Errorf("failed to compute image digest: %v", err)
+	}
+
+	if err := p.checkImmutableTags(digest.String(), tags, nameOpts, remoteOpts); err != nil {
+		return err
+	}
+
+	push := p.PushImageToRegistry
+	if push == nil {
+		push = func(img v1.Image, dest string) error {
+			ref, err := name.ParseReference(dest, nameOpts...)
+			if err != nil {
+				return err
+			}
+			return remote.Write(ref, img, remoteOpts...)
+		}
+	}
+
+	for _, tag := range tags {
+		dest := fmt.Sprintf("%s:%s", p.Build.Repo, tag)
+		if src.index != nil {
+			ref, err := name.ParseReference(dest, nameOpts...)
+			if err != nil {
+				return fmt.Errorf("invalid destination %s: %v", dest, err)
+			}
+			if err := remote.WriteIndex(ref, src.index, remoteOpts...); err != nil {
+				return fmt.Errorf("failed to push image index from [%s] to destination [%s]: %v", src.from, dest, err)
+			}
+			fmt.Printf("Successfully pushed image index - '%s'\n to %s\n", dest, p.Build.Repo)
+			continue
+		}
+		if err := push(src.image, dest); err != nil {
+			return fmt.Errorf("failed to push image from [%s] to destination [%s]: %v", src.from, dest, err)
+		}
+		fmt.Printf("Successfully pushed image - '%s'\n to %s\n", dest, p.Build.Repo)
+	}
+
+	if p.Build.VerifyPush {
+		if err := p.verifyTags(digest.String(), tags); err != nil {
+			return err
+		}
+	}
+
+	if p.Build.DigestFile != "" {
+		if err := os.WriteFile(p.Build.DigestFile, []byte(digest.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write digest file at path: %s: %v", p.Build.DigestFile, err)
+		}
+	}
+
+	if p.Artifact.ArtifactFile != "" {
+		err := artifact.WritePluginArtifactFile(p.Artifact.RegistryType, p.Artifact.ArtifactFile, p.Artifact.Registry, p.Artifact.Repo, digest.String(), tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write plugin artifact file at path: %s with error: %s\n", p.Artifact.ArtifactFile, err)
+		}
+	}
+
+	outputFile := os.Getenv("DRONE_OUTPUT")
+	if err := output.WritePluginOutputFileTags(outputFile, digest.String(), "", tags); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write plugin output file at path: %s with error: %s\n", outputFile, err)
+	}
+	return nil
+}
+
+// promote copies the image or image index referenced by PromoteFrom to every
+// resolved tag of the build, keeping its digest.
+func (p Plugin) promote() error {
+	tags, err := p.Build.ResolveTags()
+	if err != nil {
+		return err
+	}
+
+	ref, err := name.ParseReference(p.Build.PromoteFrom, p.Build.nameOptions()...)
+	if err != nil {
+		return fmt.Errorf("invalid promote-from reference %s: %v", p.Build.PromoteFrom, err)
+	}
+	desc, err := remote.Get(ref, p.remoteOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", ref, err)
+	}
+
+	src := pushSource{from: ref.String()}
+	if desc.MediaType.IsIndex() {
+		src.index, err = desc.ImageIndex()
+	} else {
+		src.image, err = desc.Image()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+	fmt.Printf("Promoting %s@%s\n", ref.Context(), desc.Digest)
+
+	return p.publish(src, tags, p.Build.nameOptions(), p.remoteOptions())
+}
+
+// loadSource loads the image or image index to push from SourceTarPath.
+func (p Plugin) loadSource() (pushSource, error) {
+	info, err := os.Stat(p.Build.SourceTarPath)
+	if err != nil {
+		return pushSource{}, fmt.Errorf("failed to read push-only source: %v", err)
+	}
+	if info.IsDir() {
+		return loadLayout(p.Build.SourceTarPath, p.Build.SourceTag)
+	}
+	if p.LoadImageFromTarball != nil {
+		img, err := p.LoadImageFromTarball(p.Build.SourceTarPath)
+		if err != nil {
+			return pushSource{}, fmt.Errorf("failed to load image from tarball: %v", err)
+		}
+		return pushSource{from: p.Build.SourceTarPath, image: img}, nil
+	}
+	return loadTarball(p.Build.SourceTarPath, p.Build.SourceTag)
+}
+
+// loadTarball loads an image from a docker tarball. Tarballs that contain
+// several images require the tag of the image to load.
+func loadTarball(path, sourceTag string) (pushSource, error) {
+	opener := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	manifest, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return pushSource{}, fmt.Errorf("failed to load image from tarball: %v", err)
+	}
+
+	var tag *name.Tag
+	if sourceTag != "" {
+		repoTag, err := findRepoTag(manifest, sourceTag)
+		if err != nil {
+			return pushSource{}, err
+		}
+		t, err := name.NewTag(repoTag)
+		if err != nil {
+			return pushSource{}, fmt.Errorf("invalid tag %s in tarball: %v", repoTag, err)
+		}
+		tag = &t
+	} else if len(manifest) > 1 {
+		return pushSource{}, fmt.Errorf("tarball %s contains %d images, set source-tag to one of: %s", path, len(manifest), strings.Join(repoTags(manifest), ", "))
+	}
+
+	img, err := tarball.Image(opener, tag)
+	if err != nil {
+		return pushSource{}, fmt.Errorf("failed to load image from tarball: %v", err)
+	}
+	return pushSource{from: path, image: img}, nil
+}
+
+// findRepoTag returns the repo tag of the tarball matching the source tag,
+// given either in full ("foo/bar:v1") or as the tag alone ("v1").
+func findRepoTag(manifest tarball.Manifest, sourceTag string) (string, error) {
+	var matches []string
+	for _, repoTag := range repoTags(manifest) {
+		if repoTag == sourceTag {
+			return repoTag, nil
+		}
+		if strings.HasSuffix(repoTag, ":"+sourceTag) {
+			matches = append(matches, repoTag)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("tag %s not found in tarball, available tags: %s", sourceTag, strings.Join(repoTags(manifest), ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("tag %s matches several images in tarball: %s", sourceTag, strings.Join(matches, ", "))
+	}
+}
+
+// repoTags returns every repo tag of the tarball.
+func repoTags(manifest tarball.Manifest) []string {
+	var tags []string
+	for _, desc := range manifest {
+		tags = append(tags, desc.RepoTags...)
+	}
+	return tags
+}
+
+// loadLayout loads an image or image index from an OCI image layout. A
+// layout that references a single image or index yields it, a layout with
+// several manifests is pushed as an index, unless the source tag selects one
+// of them by its ref name annotation.
+func loadLayout(path, sourceTag string) (pushSource, error) {
+	idx, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return pushSource{}, fmt.Errorf("failed to load OCI layout: %v", err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return pushSource{}, fmt.Errorf("failed to read OCI layout index: %v", err)
+	}
+
+	manifests := manifest.Manifests
+	if sourceTag != "" {
+		manifests = nil
+		for _, desc := range manifest.Manifests {
+			if desc.Annotations[refNameAnnotation] == sourceTag {
+				manifests = append(manifests, desc)
+			}
+		}
+		if len(manifests) != 1 {
+			return pushSource{}, fmt.Errorf("tag %s matches %d manifests in OCI layout %s", sourceTag, len(manifests), path)
+		}
+	}
+
+	switch {
+	case len(manifests) == 0:
+		return pushSource{}, fmt.Errorf("OCI layout %s contains no images", path)
+	case len(manifests) > 1:
+		return pushSource{from: path, index: idx}, nil
+	}
+
+	desc := manifests[0]
+	switch {
+	case desc.MediaType.IsIndex():
+		child, err := idx.ImageIndex(desc.Digest)
+		if err != nil {
+			return pushSource{}, fmt.Errorf("failed to load image index from OCI layout: %v", err)
+		}
+		return pushSource{from: path, index: child}, nil
+	case desc.MediaType.IsImage():
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			return pushSource{}, fmt.Errorf("failed to load image from OCI layout: %v", err)
+		}
+		return pushSource{from: path, image: img}, nil
+	default:
+		return pushSource{}, fmt.Errorf("unsupported media type %s in OCI layout %s", desc.MediaType, path)
+	}
+}
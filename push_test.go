@@ -0,0 +1,346 @@
+package kaniko
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/drone/drone-kaniko/pkg/artifact"
+)
+
+func TestResolveTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   Build
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "tags",
+			build: Build{Tags: []string{"latest", "v1.2.3"}},
+			want:  []string{"latest", "v1.2.3"},
+		},
+		{
+			name:  "expand_tag",
+			build: Build{Tags: []string{"v1.2.3"}, ExpandTag: true},
+			want:  []string{"1", "1.2", "1.2.3"},
+		},
+		{
+			name: "auto_tag",
+			build: Build{
+				Tags:           []string{"latest"},
+				AutoTag:        true,
+				AutoTagSuffix:  "linux-amd64",
+				DroneCommitRef: "refs/tags/v1.2.3",
+			},
+			want: []string{"1-linux-amd64", "1.2-linux-amd64", "1.2.3-linux-amd64"},
+		},
+		{
+			name:    "auto_tag_conflicts_with_expand_tag",
+			build:   Build{AutoTag: true, ExpandTag: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build.ResolveTags()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Unexpected tags (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestPushTarball(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference("foo/bar:latest")
+	sourceTarPath := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(sourceTarPath, ref, img); err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := img.Digest()
+
+	artifactFile := filepath.Join(dir, "artifact.json")
+	t.Setenv("DRONE_OUTPUT", filepath.Join(dir, "output.env"))
+
+	p := Plugin{
+		Build: Build{
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"v1.2.3"},
+			ExpandTag:     true,
+			PushOnly:      true,
+			SourceTarPath: sourceTarPath,
+		},
+		Artifact: Artifact{
+			Repo:         host + "/foo/bar",
+			ArtifactFile: artifactFile,
+			RegistryType: artifact.Docker,
+		},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tag := range []string{"1", "1.2", "1.2.3"} {
+		ref, _ := name.ParseReference(fmt.Sprintf("%s/foo/bar:%s", host, tag))
+		desc, err := remote.Head(ref)
+		if err != nil {
+			t.Fatalf("missing tag %s: %v", tag, err)
+		}
+		if desc.Digest != digest {
+			t.Errorf("digest of tag %s = %s, want %s", tag, desc.Digest, digest)
+		}
+	}
+
+	b, err := os.ReadFile(artifactFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got artifact.DockerArtifact
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	var images []string
+	for _, image := range got.Data.Images {
+		images = append(images, image.Image)
+	}
+	want := []string{host + "/foo/bar:1", host + "/foo/bar:1.2", host + "/foo/bar:1.2.3"}
+	if !cmp.Equal(images, want) {
+		t.Errorf("Unexpected artifact images (-want +got):\n%s", cmp.Diff(want, images))
+	}
+}
+
+func TestPushMultiImageTarball(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+
+	refs := make(map[name.Reference]v1.Image)
+	digests := make(map[string]v1.Hash)
+	for _, tag := range []string{"v1", "v2"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, _ := name.ParseReference("foo/bar:" + tag)
+		refs[ref] = img
+		digests[tag], _ = img.Digest()
+	}
+	sourceTarPath := filepath.Join(dir, "images.tar")
+	if err := tarball.MultiRefWriteToFile(sourceTarPath, refs); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Plugin{
+		Build: Build{
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"latest"},
+			PushOnly:      true,
+			SourceTarPath: sourceTarPath,
+		},
+	}
+	if err := p.Exec(); err == nil {
+		t.Errorf("Expected an error without source tag, but got none")
+	}
+
+	p.Build.SourceTag = "v2"
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ref, _ := name.ParseReference(host + "/foo/bar:latest")
+	desc, err := remote.Head(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Digest != digests["v2"] {
+		t.Errorf("pushed digest = %s, want %s", desc.Digest, digests["v2"])
+	}
+}
+
+func TestPushLayoutIndex(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+
+	idx, err := random.Index(64, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := layout.Write(filepath.Join(dir, "layout"), empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := path.AppendIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := idx.Digest()
+
+	p := Plugin{
+		Build: Build{
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"v1.2.3"},
+			PushOnly:      true,
+			SourceTarPath: string(path),
+		},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ref, _ := name.ParseReference(host + "/foo/bar:v1.2.3")
+	got, err := remote.Index(ref)
+	if err != nil {
+		t.Fatalf("failed to fetch pushed index: %v", err)
+	}
+	gotDigest, _ := got.Digest()
+	if gotDigest != digest {
+		t.Errorf("pushed index digest = %s, want %s", gotDigest, digest)
+	}
+	manifest, _ := got.IndexManifest()
+	if len(manifest.Manifests) != 2 {
+		t.Errorf("pushed index manifests = %d, want 2", len(manifest.Manifests))
+	}
+}
+
+func TestPromote(t *testing.T) {
+	source := newTestRegistry(t)
+	dest := newTestRegistry(t)
+	dir := t.TempDir()
+
+	idx, err := random.Index(64, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, _ := name.ParseReference(source + "/staging/app:abc123")
+	if err := remote.WriteIndex(src, idx); err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := idx.Digest()
+	artifactFile := filepath.Join(dir, "artifact.json")
+
+	p := Plugin{
+		Build: Build{
+			Repo:        dest + "/prod/app",
+			Tags:        []string{"v1.2.3"},
+			ExpandTag:   true,
+			PromoteFrom: fmt.Sprintf("%s/staging/app@%s", source, digest),
+			DigestFile:  filepath.Join(dir, "digest"),
+		},
+		Artifact: Artifact{
+			Repo:         dest + "/prod/app",
+			ArtifactFile: artifactFile,
+			RegistryType: artifact.Docker,
+		},
+	}
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tag := range []string{"1", "1.2", "1.2.3"} {
+		ref, _ := name.ParseReference(fmt.Sprintf("%s/prod/app:%s", dest, tag))
+		desc, err := remote.Head(ref)
+		if err != nil {
+			t.Fatalf("missing tag %s: %v", tag, err)
+		}
+		if desc.Digest != digest {
+			t.Errorf("digest of tag %s = %s, want %s", tag, desc.Digest, digest)
+		}
+	}
+	if got := getDigest(p.Build.DigestFile); got != digest.String() {
+		t.Errorf("digest file = %s, want %s", got, digest)
+	}
+
+	b, err := os.ReadFile(artifactFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got artifact.DockerArtifact
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range got.Data.Images {
+		if image.Digest != digest.String() {
+			t.Errorf("artifact image %s digest = %s, want %s", image.Image, image.Digest, digest)
+		}
+	}
+}
+
+func TestPromoteConflicts(t *testing.T) {
+	for _, build := range []Build{
+		{Repo: "foo/bar", PromoteFrom: "foo/baz:latest", PushOnly: true, SourceTarPath: "image.tar"},
+		{Repo: "foo/bar", PromoteFrom: "foo/baz:latest", NoPush: true},
+	} {
+		if err := (Plugin{Build: build}).Exec(); err == nil {
+			t.Errorf("Expected an error for %+v, but got none", build)
+		}
+	}
+}
+
+func TestPushKeychain(t *testing.T) {
+	// the registry requires basic auth, which only the keychain of the plugin
+	// holds credentials for
+	reg := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "foo" || pass != "bar" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference("foo/bar:latest")
+	sourceTarPath := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(sourceTarPath, ref, img); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DRONE_OUTPUT", "")
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	p := Plugin{
+		Build: Build{
+			Repo:          host + "/foo/bar",
+			Tags:          []string{"latest"},
+			PushOnly:      true,
+			VerifyPush:    true,
+			SourceTarPath: sourceTarPath,
+		},
+	}
+	if err := p.Push(); err == nil {
+		t.Fatalf("Expected an error pushing without credentials")
+	}
+
+	p.Keychain = RegistryKeychain(host, &authn.Basic{Username: "foo", Password: "bar"})
+	if err := p.Push(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
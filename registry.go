@@ -0,0 +1,201 @@
+package kaniko
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// nameOptions returns the options used to parse image references for
+// registry calls made by the plugin itself.
+func (b Build) nameOptions() []name.Option {
+	if b.Insecure {
+		return []name.Option{name.Insecure}
+	}
+	return nil
+}
+
+// remoteOptions returns the options used for registry calls made by the
+// plugin itself. Unless a keychain is set, credentials are resolved from the
+// same docker config that is written for the kaniko executor.
+func (p Plugin) remoteOptions() []remote.Option {
+	return []remote.Option{
+		remote.WithAuthFromKeychain(p.keychain()),
+		remote.WithTransport(p.transport()),
+	}
+}
+
+// CallsRegistry returns true if the plugin calls the registries itself,
+// rather than only through the executor, in which case the binaries set a
+// Keychain for the registries the docker config holds no credentials for.
+func (b Build) CallsRegistry() bool {
+	return b.PromoteFrom != "" ||
+		b.PushOnly ||
+		b.Preflight ||
+		b.VerifyPush ||
+		b.TagRemotely ||
+		b.Dedup ||
+		b.FloatingTags ||
+		len(b.ImmutableTags) > 0 ||
+		len(b.Platforms) > 0
+}
+
+// keychain returns the keychain of the plugin, defaulting to the docker
+// config.
+func (p Plugin) keychain() authn.Keychain {
+	if p.Keychain == nil {
+		return authn.DefaultKeychain
+	}
+	return p.Keychain
+}
+
+// transport returns the transport for registry calls, which skips TLS
+// verification when the build does.
+func (p Plugin) transport() http.RoundTripper {
+	if b := p.Build; b.SkipTlsVerify || b.SkipTLSVerify || b.SkipTLSVerifyRegistry {
+		transport := remote.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		return transport
+	}
+	return remote.DefaultTransport
+}
+
+// RegistryKeychain returns a keychain that authenticates to the registry
+// with auth and falls back to the docker config for every other registry.
+func RegistryKeychain(registry string, auth authn.Authenticator) authn.Keychain {
+	return authn.NewMultiKeychain(registryKeychain{registry: registry, auth: auth}, authn.DefaultKeychain)
+}
+
+// registryKeychain resolves a fixed authenticator for a single registry.
+type registryKeychain struct {
+	registry string
+	auth     authn.Authenticator
+}
+
+// Resolve implements authn.Keychain.
+func (k registryKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	registry, err := name.NewRegistry(k.registry)
+	if err != nil || registry.RegistryStr() != target.RegistryStr() {
+		return authn.Anonymous, nil
+	}
+	return k.auth, nil
+}
+
+// tagRetries is the number of attempts made to create each remote tag.
+const tagRetries = 3
+
+// tagRetryDelay is the delay before the first retry, doubled on every retry.
+var tagRetryDelay = time.Second
+
+// tagRemotely points every label of the repository at the pushed digest with
+// a manifest-only PUT. Each label is retried on failure, and all failures are
+// reported together once every label was attempted.
+func (p Plugin) tagRemotely(digest string, labels []string) error {
+	if digest == "" {
+		return fmt.Errorf("no digest was reported for %s, cannot create remote tags %s", p.Build.Repo, strings.Join(labels, ", "))
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s@%s", p.Build.Repo, digest), p.Build.nameOptions()...)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %s@%s: %v", p.Build.Repo, digest, err)
+	}
+	var desc *remote.Descriptor
+	err = retry(tagRetries, tagRetryDelay, func() (err error) {
+		desc, err = remote.Get(ref, p.remoteOptions()...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch pushed image %s: %v", ref, err)
+	}
+
+	var failed []string
+	for _, label := range labels {
+		tag, err := name.NewTag(fmt.Sprintf("%s:%s", p.Build.Repo, label), p.Build.nameOptions()...)
+		if err == nil {
+			err = retry(tagRetries, tagRetryDelay, func() error {
+				return remote.Tag(tag, desc, p.remoteOptions()...)
+			})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to tag %s as %s:%s: %v\n", digest, p.Build.Repo, label, err)
+			failed = append(failed, fmt.Sprintf("%s:%s: %v", p.Build.Repo, label, err))
+			continue
+		}
+		fmt.Printf("Successfully tagged %s as %s\n", digest, tag)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create %d of %d remote tags after %d attempts each:\n%s", len(failed), len(labels), tagRetries, strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// readDigest returns the digest written to the digest file. Unlike getDigest
+// it fails when the file is missing or empty.
+func readDigest(digestFile string) (string, error) {
+	if digestFile == "" {
+		return "", fmt.Errorf("a digest file is required to verify the pushed tags")
+	}
+	content, err := os.ReadFile(digestFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read digest file at path: %s: %v", digestFile, err)
+	}
+	digest := strings.TrimSpace(string(content))
+	if digest == "" {
+		return "", fmt.Errorf("digest file at path: %s is empty", digestFile)
+	}
+	return digest, nil
+}
+
+// verifyTags checks that every label of the repository resolves to the
+// digest. Each label is retried to ride out eventually consistent registries,
+// and all failures are reported together.
+func (p Plugin) verifyTags(digest string, labels []string) error {
+	var failed []string
+	for _, label := range labels {
+		ref, err := name.ParseReference(fmt.Sprintf("%s:%s", p.Build.Repo, label), p.Build.nameOptions()...)
+		if err == nil {
+			err = retry(tagRetries, tagRetryDelay, func() error {
+				desc, err := remote.Head(ref, p.remoteOptions()...)
+				if err != nil {
+					return err
+				}
+				if desc.Digest.String() != digest {
+					return fmt.Errorf("tag resolves to %s, want %s", desc.Digest, digest)
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s:%s: %v", p.Build.Repo, label, err))
+			continue
+		}
+		fmt.Printf("Verified %s resolves to %s\n", ref, digest)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("push verification failed for %d of %d tags:\n%s", len(failed), len(labels), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// retry calls fn until it succeeds or the attempts are used up, doubling the
+// delay between attempts. It returns the last error.
+func retry(attempts int, delay time.Duration, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			fmt.Fprintf(os.Stderr, "retrying in %s: %v\n", delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+package kaniko
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestRegistryKeychain(t *testing.T) {
+	auth := &authn.Basic{Username: "foo", Password: "bar"}
+	keychain := RegistryKeychain("123456789012.dkr.ecr.us-east-1.amazonaws.com", auth)
+
+	ref, _ := name.ParseReference("123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest")
+	got, err := keychain.Resolve(ref.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != auth {
+		t.Errorf("Resolve() = %v, want the registry authenticator", got)
+	}
+
+	other, _ := name.ParseReference("us-docker.pkg.dev/project/app:latest")
+	if got, _ := keychain.Resolve(other.Context()); got == auth {
+		t.Errorf("Resolve() returned the registry authenticator for %s", other.Context().RegistryStr())
+	}
+}
+
+func TestCallsRegistry(t *testing.T) {
+	tests := []struct {
+		name  string
+		build Build
+		want  bool
+	}{
+		{name: "build", build: Build{Repo: "foo/bar", Tags: []string{"latest"}}},
+		{name: "promote", build: Build{PromoteFrom: "foo/bar:rc"}, want: true},
+		{name: "push only", build: Build{PushOnly: true}, want: true},
+		{name: "preflight", build: Build{Preflight: true}, want: true},
+		{name: "verify push", build: Build{VerifyPush: true}, want: true},
+		{name: "tag remotely", build: Build{TagRemotely: true}, want: true},
+		{name: "dedup", build: Build{Dedup: true}, want: true},
+		{name: "floating tags", build: Build{FloatingTags: true}, want: true},
+		{name: "immutable tags", build: Build{ImmutableTags: []string{"all"}}, want: true},
+		{name: "platforms", build: Build{Platforms: []string{"linux/amd64"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.build.CallsRegistry(); got != tt.want {
+				t.Errorf("CallsRegistry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecTagRemotely(t *testing.T) {
+	// reject manifest PUTs for the 1.2 tag to exercise retries and reporting
+	var rejected int
+	reg := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/manifests/1.2") {
+			rejected++
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	delay := tagRetryDelay
+	tagRetryDelay = 0
+	t.Cleanup(func() { tagRetryDelay = delay })
+
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var destinations []string
+	executor := fakeExecutor(t)
+	p := Plugin{
+		Build: Build{
+			Dockerfile:  dockerfile,
+			Context:     dir,
+			Repo:        host + "/foo/bar",
+			Tags:        []string{"v1.2.3"},
+			ExpandTag:   true,
+			TagRemotely: true,
+		},
+		Runner: RunnerFunc(func(e Execution) (Result, error) {
+			for _, arg := range e.Args {
+				if strings.HasPrefix(arg, "--destination=") {
+					destinations = append(destinations, arg)
+				}
+			}
+			return executor.Run(e)
+		}),
+	}
+	err := p.Exec()
+	if err == nil || !strings.Contains(err.Error(), "foo/bar:1.2:") {
+		t.Fatalf("Expected an error reporting the 1.2 tag, got %v", err)
+	}
+	if rejected != tagRetries {
+		t.Errorf("tag 1.2 attempts = %d, want %d", rejected, tagRetries)
+	}
+	if want := []string{"--destination=" + host + "/foo/bar:1"}; !cmp.Equal(destinations, want) {
+		t.Errorf("executor destinations = %q, want %q", destinations, want)
+	}
+
+	primary, _ := name.ParseReference(host + "/foo/bar:1")
+	want, err := remote.Head(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference(host + "/foo/bar:1.2.3")
+	got, err := remote.Head(ref)
+	if err != nil {
+		t.Fatalf("missing remote tag 1.2.3: %v", err)
+	}
+	if got.Digest != want.Digest {
+		t.Errorf("digest of tag 1.2.3 = %s, want %s", got.Digest, want.Digest)
+	}
+}
+
+func TestReadDigest(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	valid := filepath.Join(dir, "digest")
+	if err := os.WriteFile(valid, []byte("sha256:abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"", filepath.Join(dir, "missing"), empty} {
+		if _, err := readDigest(path); err == nil {
+			t.Errorf("readDigest(%q) expected an error, but got none", path)
+		}
+	}
+	if got, err := readDigest(valid); err != nil || got != "sha256:abc" {
+		t.Errorf("readDigest() = %q, %v, want sha256:abc", got, err)
+	}
+}
+
+func TestVerifyTags(t *testing.T) {
+	host := newTestRegistry(t)
+	delay := tagRetryDelay
+	tagRetryDelay = 0
+	t.Cleanup(func() { tagRetryDelay = delay })
+
+	var digest string
+	for _, tag := range []string{"built", "other"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, _ := name.ParseReference(host + "/foo/bar:" + tag)
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+		if tag == "built" {
+			d, _ := img.Digest()
+			digest = d.String()
+		}
+	}
+
+	p := Plugin{Build: Build{Repo: host + "/foo/bar"}}
+	if err := p.verifyTags(digest, []string{"built"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	err := p.verifyTags(digest, []string{"built", "other", "missing"})
+	if err == nil {
+		t.Fatal("Expected an error, but got none")
+	}
+	for _, want := range []string{"2 of 3 tags", "foo/bar:other: tag resolves to", "foo/bar:missing:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestExecVerifyPushEmptyDigest(t *testing.T) {
+	host := newTestRegistry(t)
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := Plugin{
+		Build: Build{
+			Dockerfile: dockerfile,
+			Context:    dir,
+			Repo:       host + "/foo/bar",
+			Tags:       []string{"latest"},
+			DigestFile: filepath.Join(dir, "digest"),
+			VerifyPush: true,
+		},
+		Runner: RunnerFunc(func(Execution) (Result, error) {
+			return Result{}, os.WriteFile(filepath.Join(dir, "digest"), nil, 0644)
+		}),
+	}
+	if err := p.Exec(); err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Errorf("Expected an empty digest file error, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package kaniko
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// shortSHALength is the length of the abbreviated commit SHA.
+const shortSHALength = 8
+
+// TemplateData defines the Drone metadata available to tag, label and build
+// arg templates, e.g. "{{ .Branch }}-{{ .ShortSHA }}".
+type TemplateData struct {
+	SHA         string    // Commit SHA
+	ShortSHA    string    // Commit SHA abbreviated to 8 characters
+	Branch      string    // Commit branch
+	Tag         string    // Commit tag
+	Ref         string    // Commit ref
+	BuildNumber string    // Build number
+	PullRequest string    // Pull request number
+	Repo        string    // Repository name, e.g. octocat/hello-world
+	RepoLink    string    // Repository web link
+	Remote      string    // Repository clone URL
+	Event       string    // Build event, e.g. push or pull_request
+	Timestamp   time.Time // Build creation time
+}
+
+// Date formats the build creation time in UTC using the Go time layout,
+// e.g. {{ .Date "20060102" }}.
+func (d TemplateData) Date(layout string) string {
+	return d.Timestamp.UTC().Format(layout)
+}
+
+// templateFuncs are the functions available to templates in addition to the
+// builtins.
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+}
+
+// NewTemplateData returns the template data read from the DRONE_* variables
+// of the environment, falling back to their CI_* counterparts.
+func NewTemplateData(environ []string) TemplateData {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	lookup := func(keys ...string) string {
+		for _, key := range keys {
+			if value := env[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	d := TemplateData{
+		SHA:         lookup("DRONE_COMMIT_SHA", "CI_COMMIT_SHA"),
+		Branch:      lookup("DRONE_COMMIT_BRANCH", "DRONE_BRANCH", "CI_COMMIT_BRANCH"),
+		Tag:         lookup("DRONE_TAG", "CI_COMMIT_TAG"),
+		Ref:         lookup("DRONE_COMMIT_REF", "CI_COMMIT_REF"),
+		BuildNumber: lookup("DRONE_BUILD_NUMBER", "CI_BUILD_NUMBER"),
+		PullRequest: lookup("DRONE_PULL_REQUEST", "CI_COMMIT_PULL_REQUEST"),
+		Repo:        lookup("DRONE_REPO", "CI_REPO"),
+		RepoLink:    lookup("DRONE_REPO_LINK", "CI_REPO_LINK"),
+		Remote:      lookup("DRONE_GIT_HTTP_URL", "DRONE_REMOTE_URL", "CI_REMOTE_URL"),
+		Event:       lookup("DRONE_BUILD_EVENT", "CI_BUILD_EVENT"),
+		Timestamp:   time.Now(),
+	}
+	d.ShortSHA = d.SHA
+	if len(d.ShortSHA) > shortSHALength {
+		d.ShortSHA = d.ShortSHA[:shortSHALength]
+	}
+	if created, err := strconv.ParseInt(lookup("DRONE_BUILD_CREATED", "CI_BUILD_CREATED"), 10, 64); err == nil {
+		d.Timestamp = time.Unix(created, 0)
+	}
+	return d
+}
+
+// renderTemplate renders s as a Go template. Strings without template
+// actions are returned unchanged.
+func renderTemplate(s string, data TemplateData) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// renderTemplates renders every value, kind names the values in errors.
+func renderTemplates(kind string, values []string, data TemplateData) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	rendered := make([]string, len(values))
+	for i, value := range values {
+		out, err := renderTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template %q: %v", kind, value, err)
+		}
+		if out == "" && kind == "tag" {
+			return nil, fmt.Errorf("%s template %q rendered an empty tag", kind, value)
+		}
+		rendered[i] = out
+	}
+	return rendered, nil
+}
+
+// withTemplates returns the plugin with the tag templates rendered from the
+// Drone metadata of the environment. Label and build arg templates are only
+// rendered when RenderTemplates is set, as their values may hold literal
+// templates, e.g. Helm values, that are meant for the build.
+func (p Plugin) withTemplates() (Plugin, error) {
+	data := NewTemplateData(os.Environ())
+
+	var err error
+	if p.Build.Tags, err = renderTemplates("tag", p.Build.Tags, data); err != nil {
+		return p, err
+	}
+	if p.Artifact.Tags, err = renderTemplates("tag", p.Artifact.Tags, data); err != nil {
+		return p, err
+	}
+	if !p.Build.RenderTemplates {
+		return p, nil
+	}
+	if p.Build.Labels, err = renderTemplates("label", p.Build.Labels, data); err != nil {
+		return p, err
+	}
+	if p.Build.Args, err = renderTemplates("build arg", p.Build.Args, data); err != nil {
+		return p, err
+	}
+	if p.Build.ArgsNew, err = renderTemplates("build arg", p.Build.ArgsNew, data); err != nil {
+		return p, err
+	}
+	return p, nil
+}
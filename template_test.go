@@ -0,0 +1,155 @@
+package kaniko
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	got := NewTemplateData([]string{
+		"DRONE_COMMIT_SHA=8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+		"CI_COMMIT_BRANCH=main",
+		"DRONE_TAG=v1.2.3",
+		"DRONE_BUILD_NUMBER=42",
+		"DRONE_PULL_REQUEST=7",
+		"DRONE_BUILD_CREATED=1700000000",
+	})
+	want := TemplateData{
+		SHA:         "8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+		ShortSHA:    "8f51ad78",
+		Branch:      "main",
+		Tag:         "v1.2.3",
+		BuildNumber: "42",
+		PullRequest: "7",
+		Timestamp:   time.Unix(1700000000, 0),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected template data (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	data := TemplateData{
+		SHA:         "8f51ad7884c5eb69c11d260a31da7a745e6b78e2",
+		ShortSHA:    "8f51ad78",
+		Branch:      "feature/login",
+		BuildNumber: "42",
+		Timestamp:   time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name    string
+		kind    string
+		values  []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "literal",
+			kind:   "tag",
+			values: []string{"latest"},
+			want:   []string{"latest"},
+		},
+		{
+			name:   "branch_and_sha",
+			kind:   "tag",
+			values: []string{`{{ replace "/" "-" .Branch }}-{{ .ShortSHA }}`},
+			want:   []string{"feature-login-8f51ad78"},
+		},
+		{
+			name:   "date_and_build_number",
+			kind:   "tag",
+			values: []string{`{{ .Date "20060102" }}.{{ .BuildNumber }}`},
+			want:   []string{"20240301.42"},
+		},
+		{
+			name:   "build_arg",
+			kind:   "build arg",
+			values: []string{"GIT_SHA={{ .SHA }}"},
+			want:   []string{"GIT_SHA=8f51ad7884c5eb69c11d260a31da7a745e6b78e2"},
+		},
+		{
+			name:    "unknown_field",
+			kind:    "label",
+			values:  []string{"commit={{ .Commit }}"},
+			wantErr: `invalid label template "commit={{ .Commit }}"`,
+		},
+		{
+			name:    "syntax_error",
+			kind:    "tag",
+			values:  []string{"{{ .Branch "},
+			wantErr: "invalid tag template",
+		},
+		{
+			name:    "empty_tag",
+			kind:    "tag",
+			values:  []string{"{{ .Tag }}"},
+			wantErr: "rendered an empty tag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplates(tt.kind, tt.values, data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Unexpected values (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestWithTemplates(t *testing.T) {
+	t.Setenv("DRONE_COMMIT_SHA", "8f51ad7884c5eb69c11d260a31da7a745e6b78e2")
+	t.Setenv("DRONE_COMMIT_BRANCH", "main")
+
+	// literal templates meant for the build, e.g. Helm values, are passed as
+	// they are unless templates are rendered
+	p := Plugin{Build: Build{
+		Tags:    []string{"{{ .Branch }}"},
+		Args:    []string{"VALUES={{ .Values.x }}"},
+		ArgsNew: []string{"CHART={{ .Chart.Name }}"},
+		Labels:  []string{"tpl={{ .Release.Name }}"},
+	}}
+	got, err := p.withTemplates()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := Build{
+		Tags:    []string{"main"},
+		Args:    []string{"VALUES={{ .Values.x }}"},
+		ArgsNew: []string{"CHART={{ .Chart.Name }}"},
+		Labels:  []string{"tpl={{ .Release.Name }}"},
+	}
+	if diff := cmp.Diff(want, got.Build, cmp.AllowUnexported(Build{})); diff != "" {
+		t.Errorf("withTemplates() mismatch (-want +got):\n%s", diff)
+	}
+
+	p.Build.RenderTemplates = true
+	p.Build.Args = []string{"GIT_SHA={{ .ShortSHA }}"}
+	p.Build.ArgsNew = nil
+	p.Build.Labels = []string{"branch={{ .Branch }}"}
+	if got, err = p.withTemplates(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"GIT_SHA=8f51ad78"}, got.Build.Args); diff != "" {
+		t.Errorf("build args mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"branch=main"}, got.Build.Labels); diff != "" {
+		t.Errorf("labels mismatch (-want +got):\n%s", diff)
+	}
+
+	p.Build.Args = []string{"VALUES={{ .Values.x }}"}
+	if _, err := p.withTemplates(); err == nil {
+		t.Errorf("Expected an error rendering a literal template")
+	}
+}